@@ -25,6 +25,10 @@ type CreateAlumniRequest struct {
 	Email      string  `json:"email"`
 	NoTelepon  *string `json:"no_telepon,omitempty"`
 	Alamat     *string `json:"alamat,omitempty"`
+
+	// Pekerjaan -> riwayat pekerjaan yang dibuat bersama alumni dalam satu
+	// transaksi; alumni_id di tiap item diabaikan
+	Pekerjaan []CreatePekerjaanRequest `json:"pekerjaan,omitempty"`
 }
 
 type UpdateAlumniRequest struct {
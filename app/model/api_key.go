@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// APIKey -> kredensial integrasi mesin-ke-mesin; Scopes berisi nama permission
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *int       `json:"created_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // kosong = default 90 hari
+}
+
+// CreateAPIKeyResponse -> key lengkap hanya ditampilkan sekali saat dibuat
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry -> satu baris audit_log
+type AuditEntry struct {
+	ID                   int64           `json:"id"`
+	OccurredAt           time.Time       `json:"occurred_at"`
+	Action               string          `json:"action"`
+	ActorUserID          *int            `json:"actor_user_id"`
+	ActorUsername        string          `json:"actor_username"`
+	ImpersonatorUserID   *int            `json:"impersonator_user_id"`
+	ImpersonatorUsername string          `json:"impersonator_username"`
+	AuthType             string          `json:"auth_type"`
+	TargetType           string          `json:"target_type"`
+	TargetID             string          `json:"target_id"`
+	Method               string          `json:"method"`
+	Path                 string          `json:"path"`
+	Status               *int            `json:"status"`
+	IP                   string          `json:"ip"`
+	UserAgent            string          `json:"user_agent"`
+	RequestID            string          `json:"request_id"`
+	Details              json.RawMessage `json:"details"`
+	Before               json.RawMessage `json:"before"`
+	After                json.RawMessage `json:"after"`
+}
+
+// AuditFilter -> filter GET /admin/audit; field kosong berarti tidak difilter
+type AuditFilter struct {
+	Action     string
+	ActorID    *int
+	TargetType string
+	TargetID   string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditResponse struct {
+	Data []AuditEntry `json:"data"`
+	Meta MetaInfo     `json:"meta"`
+}
@@ -11,22 +11,93 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // detik sampai access token expired
+	User         User   `json:"user"`
 }
 
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // sesuai kolom di DB: password_hash
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID                 int        `json:"id"`
+	Username           string     `json:"username"`
+	Email              string     `json:"email"`
+	PasswordHash       string     `json:"-"` // sesuai kolom di DB: password_hash
+	Role               string     `json:"role"`
+	AlumniID           *int       `json:"alumni_id"` // record alumni milik akun ini, nil kalau belum dihubungkan
+	IsActive           bool       `json:"is_active"`
+	MustChangePassword bool       `json:"must_change_password"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	TOTPEnabled        bool       `json:"totp_enabled"`
+	LDAPLinked         bool       `json:"ldap_linked"` // boleh login lewat direktori LDAP dengan username yang sama
+	TokenVersion       int        `json:"-"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	AlumniID *int   `json:"alumni_id,omitempty"`
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ResetPasswordRequest -> password kosong berarti sistem membuat password sementara
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type LinkAlumniRequest struct {
+	AlumniID int `json:"alumni_id"`
 }
 
 type JWTClaims struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	Version   int    `json:"ver"` // users.token_version saat token dibuat
+	// Act -> terisi kalau token ini hasil impersonasi; berisi admin yang sebenarnya
+	Act *ActorClaim `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ActorClaim -> identitas admin di balik token impersonasi (mirip claim "act" RFC 8693)
+type ActorClaim struct {
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
-	Role     string `json:"role"`
+}
+
+// ActionClaims -> token untuk link di email dan langkah login lanjutan.
+// Tujuannya disimpan di audience supaya tidak bisa dipakai sebagai access token
+// atau untuk tujuan lain. mfa_token sekali pakai: jti-nya dicatat setelah
+// langkah kedua berhasil; link verifikasi email aman dipakai ulang karena
+// hasilnya sama.
+type ActionClaims struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email,omitempty"`
 	jwt.RegisteredClaims
 }
+
+type RegisterRequest struct {
+	NIM      string `json:"nim"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordTokenRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// LoginLock -> status percobaan login gagal untuk satu akun atau alamat IP
+type LoginLock struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
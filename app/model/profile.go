@@ -0,0 +1,19 @@
+package model
+
+// ProfileResponse -> data akun beserta record alumni yang terhubung (kalau ada)
+type ProfileResponse struct {
+	User   User    `json:"user"`
+	Alumni *Alumni `json:"alumni"`
+}
+
+// UpdateProfileRequest -> field kosong (nil) tidak diubah
+type UpdateProfileRequest struct {
+	Email     *string `json:"email,omitempty"`
+	NoTelepon *string `json:"no_telepon,omitempty"`
+	Alamat    *string `json:"alamat,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision -> satu versi data alumni/pekerjaan
+type Revision struct {
+	ID              int64           `json:"-"`
+	EntityType      string          `json:"-"`
+	EntityID        int             `json:"-"`
+	Revision        int             `json:"revision"`
+	Action          string          `json:"action"`
+	Data            json.RawMessage `json:"data"`
+	ChangedByUserID *int            `json:"changed_by_user_id"`
+	ChangedBy       string          `json:"changed_by"`
+	RequestID       string          `json:"request_id"`
+	CreatedAt       time.Time       `json:"created_at"`
+	Changes         []FieldChange   `json:"changes"`
+}
+
+// FieldChange -> perbedaan satu field terhadap revisi sebelumnya
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
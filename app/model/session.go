@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Session -> satu sesi login yang dilacak lewat refresh token
+type Session struct {
+	ID              string     `json:"id"`
+	UserID          int        `json:"user_id"`
+	AccessJTI       string     `json:"-"`
+	AccessExpiresAt time.Time  `json:"-"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      time.Time  `json:"last_used_at"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	UserAgent       string     `json:"user_agent"`
+	IP              string     `json:"ip"`
+	Device          string     `json:"device"`
+	Current         bool       `json:"current"` // sesi yang dipakai request ini
+}
+
+// SessionClient -> asal request yang membuat atau memakai sesi
+type SessionClient struct {
+	UserAgent string
+	IP        string
+	Device    string
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,42 @@
+package model
+
+// TwoFactorState -> status TOTP satu user; Secret terisi sejak setup walau belum diaktifkan
+type TwoFactorState struct {
+	Secret   *string
+	Enabled  bool
+	LastStep int64
+}
+
+// MFALoginResponse -> jawaban /login kalau password benar tapi masih perlu langkah kedua
+type MFALoginResponse struct {
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token"`
+	ExpiresIn             int64  `json:"expires_in"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// EnrollmentResponse -> recovery code hanya ditampilkan sekali saat 2FA diaktifkan
+type EnrollmentResponse struct {
+	RecoveryCodes []string       `json:"recovery_codes"`
+	Login         *LoginResponse `json:"login,omitempty"`
+}
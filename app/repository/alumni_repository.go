@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -8,22 +9,27 @@ import (
 )
 
 type AlumniRepository interface {
-	GetAll(search, sortBy, order string, limit, offset int) ([]model.Alumni, error)
-	GetByID(id int) (*model.Alumni, error)
-	Create(req model.CreateAlumniRequest) (*model.Alumni, error)
-	Update(id int, req model.UpdateAlumniRequest) (*model.Alumni, error)
-	Delete(id int) error
+	GetAll(ctx context.Context, search, sortBy, order string, limit, offset int) ([]model.Alumni, error)
+	GetByID(ctx context.Context, id int) (*model.Alumni, error)
+	GetByIDForUpdate(ctx context.Context, id int) (*model.Alumni, error)
+	GetByNIM(ctx context.Context, nim string) (*model.Alumni, error)
+	Create(ctx context.Context, req model.CreateAlumniRequest) (*model.Alumni, error)
+	Update(ctx context.Context, id int, req model.UpdateAlumniRequest) (*model.Alumni, error)
+	UpdateContact(ctx context.Context, id int, req model.UpdateProfileRequest) (*model.Alumni, error)
+	Delete(ctx context.Context, id int) error
 }
 
 type alumniRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewAlumniRepository(db *sql.DB) AlumniRepository {
+func NewAlumniRepository(db DBTX) AlumniRepository {
 	return &alumniRepository{db: db}
 }
 
-func (r *alumniRepository) GetAll(search, sortBy, order string, limit, offset int) ([]model.Alumni, error) {
+func (r *alumniRepository) GetAll(ctx context.Context, search, sortBy, order string, limit, offset int) ([]model.Alumni, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
 	query := fmt.Sprintf(`
 	SELECT id, nim, nama, jurusan, angkatan, tahun_lulus, email, no_telepon, alamat, created_at, updated_at
 	FROM alumni
@@ -32,7 +38,7 @@ func (r *alumniRepository) GetAll(search, sortBy, order string, limit, offset in
 	LIMIT $2 OFFSET $3
 	`, sortBy, order)
 
-	rows, err := r.db.Query(query, "%"+search+"%", limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, "%"+search+"%", limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +60,11 @@ func (r *alumniRepository) GetAll(search, sortBy, order string, limit, offset in
 	return alumniList, nil
 }
 
-func (r *alumniRepository) GetByID(id int) (*model.Alumni, error) {
+func (r *alumniRepository) GetByID(ctx context.Context, id int) (*model.Alumni, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
 	var a model.Alumni
-	row := r.db.QueryRow(`
+	row := r.db.QueryRowContext(ctx, `
 		SELECT id, nim, nama, jurusan, angkatan, tahun_lulus, email, no_telepon, alamat, created_at, updated_at
 		FROM alumni
 		WHERE id = $1
@@ -73,9 +81,57 @@ func (r *alumniRepository) GetByID(id int) (*model.Alumni, error) {
 	return &a, nil
 }
 
-func (r *alumniRepository) Create(req model.CreateAlumniRequest) (*model.Alumni, error) {
+// GetByIDForUpdate -> GetByID yang mengunci baris sampai transaksi selesai,
+// dipakai di unit of work supaya pekerjaan baru tidak bisa ditautkan ke
+// alumni yang sedang diubah atau dihapus
+func (r *alumniRepository) GetByIDForUpdate(ctx context.Context, id int) (*model.Alumni, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var a model.Alumni
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, nim, nama, jurusan, angkatan, tahun_lulus, email, no_telepon, alamat, created_at, updated_at
+		FROM alumni
+		WHERE id = $1
+		FOR UPDATE
+	`, id)
+
+	err := row.Scan(
+		&a.ID, &a.NIM, &a.Nama, &a.Jurusan, &a.Angkatan,
+		&a.TahunLulus, &a.Email, &a.NoTelepon, &a.Alamat,
+		&a.CreatedAt, &a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *alumniRepository) GetByNIM(ctx context.Context, nim string) (*model.Alumni, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var a model.Alumni
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, nim, nama, jurusan, angkatan, tahun_lulus, email, no_telepon, alamat, created_at, updated_at
+		FROM alumni
+		WHERE nim = $1
+	`, nim)
+
+	err := row.Scan(
+		&a.ID, &a.NIM, &a.Nama, &a.Jurusan, &a.Angkatan,
+		&a.TahunLulus, &a.Email, &a.NoTelepon, &a.Alamat,
+		&a.CreatedAt, &a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *alumniRepository) Create(ctx context.Context, req model.CreateAlumniRequest) (*model.Alumni, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
 	var id int
-	err := r.db.QueryRow(`
+	err := r.db.QueryRowContext(ctx, `
 		INSERT INTO alumni (nim, nama, jurusan, angkatan, tahun_lulus, email, no_telepon, alamat, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id
@@ -85,11 +141,13 @@ func (r *alumniRepository) Create(req model.CreateAlumniRequest) (*model.Alumni,
 		return nil, err
 	}
 
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
 }
 
-func (r *alumniRepository) Update(id int, req model.UpdateAlumniRequest) (*model.Alumni, error) {
-	result, err := r.db.Exec(`
+func (r *alumniRepository) Update(ctx context.Context, id int, req model.UpdateAlumniRequest) (*model.Alumni, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, `
 		UPDATE alumni
 		SET nama = $1, jurusan = $2, angkatan = $3, tahun_lulus = $4, email = $5, no_telepon = $6, alamat = $7, updated_at = $8
 		WHERE id = $9
@@ -104,11 +162,36 @@ func (r *alumniRepository) Update(id int, req model.UpdateAlumniRequest) (*model
 		return nil, sql.ErrNoRows
 	}
 
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
+}
+
+// UpdateContact -> ubah data kontak saja; field nil dibiarkan seperti semula
+func (r *alumniRepository) UpdateContact(ctx context.Context, id int, req model.UpdateProfileRequest) (*model.Alumni, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE alumni
+		SET email = COALESCE($1, email), no_telepon = COALESCE($2, no_telepon),
+			alamat = COALESCE($3, alamat), updated_at = $4
+		WHERE id = $5
+	`, req.Email, req.NoTelepon, req.Alamat, time.Now(), id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return r.GetByID(ctx, id)
 }
 
-func (r *alumniRepository) Delete(id int) error {
-	result, err := r.db.Exec("DELETE FROM alumni WHERE id = $1", id)
+func (r *alumniRepository) Delete(ctx context.Context, id int) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, "DELETE FROM alumni WHERE id = $1", id)
 	if err != nil {
 		return err
 	}
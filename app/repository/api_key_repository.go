@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tugas5/app/model"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	List(ctx context.Context) ([]model.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	Revoke(ctx context.Context, id int) error
+	TouchLastUsed(ctx context.Context, id int, ip string) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, created_by, expires_at, last_used_at, last_used_ip, revoked_at, created_at`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*model.APIKey, error) {
+	var k model.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, pq.Array(&k.Scopes), &k.CreatedBy,
+		&k.ExpiresAt, &k.LastUsedAt, &k.LastUsedIP, &k.RevokedAt, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.CreatedBy, key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	return scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix))
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TouchLastUsed -> paling banyak satu tulis per menit per key, supaya script
+// yang memanggil API berkali-kali tidak membebani database
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int, ip string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id, ip)
+	return err
+}
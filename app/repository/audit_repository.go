@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"tugas5/app/model"
+)
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry *model.AuditEntry) error
+	List(ctx context.Context, filter model.AuditFilter, limit, offset int) ([]model.AuditEntry, error)
+	Count(ctx context.Context, filter model.AuditFilter) (int, error)
+}
+
+type auditRepository struct {
+	db DBTX
+}
+
+func NewAuditRepository(db DBTX) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+const auditColumns = `id, occurred_at, action, actor_user_id, actor_username, impersonator_user_id,
+	impersonator_username, auth_type, target_type, target_id, method, path, status, ip, user_agent,
+	request_id, details, before_data, after_data`
+
+// auditWhere -> parameter $1..$7 selalu urutan auditFilterArgs
+const auditWhere = `
+	WHERE ($1 = '' OR action = $1)
+	  AND ($2::int IS NULL OR actor_user_id = $2 OR impersonator_user_id = $2)
+	  AND ($3 = '' OR target_type = $3)
+	  AND ($4 = '' OR target_id = $4)
+	  AND ($5 = '' OR request_id = $5)
+	  AND ($6::timestamptz IS NULL OR occurred_at >= $6)
+	  AND ($7::timestamptz IS NULL OR occurred_at < $7)`
+
+func auditFilterArgs(f model.AuditFilter) []any {
+	return []any{f.Action, f.ActorID, f.TargetType, f.TargetID, f.RequestID, f.From, f.To}
+}
+
+// nullJSON -> JSON kosong disimpan sebagai NULL, bukan string kosong
+func nullJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+func (r *auditRepository) Record(ctx context.Context, e *model.AuditEntry) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO audit_log (action, actor_user_id, actor_username, impersonator_user_id, impersonator_username,
+			auth_type, target_type, target_id, method, path, status, ip, user_agent, request_id, details,
+			before_data, after_data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id, occurred_at
+	`, e.Action, e.ActorUserID, e.ActorUsername, e.ImpersonatorUserID, e.ImpersonatorUsername,
+		e.AuthType, e.TargetType, e.TargetID, e.Method, e.Path, e.Status, e.IP, e.UserAgent, e.RequestID,
+		nullJSON(e.Details), nullJSON(e.Before), nullJSON(e.After)).
+		Scan(&e.ID, &e.OccurredAt)
+}
+
+// List -> terbaru lebih dulu
+func (r *auditRepository) List(ctx context.Context, filter model.AuditFilter, limit, offset int) ([]model.AuditEntry, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	args := append(auditFilterArgs(filter), limit, offset)
+	rows, err := r.db.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_log`+auditWhere+`
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $8 OFFSET $9
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var e model.AuditEntry
+		var details, before, after []byte
+		if err := rows.Scan(&e.ID, &e.OccurredAt, &e.Action, &e.ActorUserID, &e.ActorUsername,
+			&e.ImpersonatorUserID, &e.ImpersonatorUsername, &e.AuthType, &e.TargetType, &e.TargetID,
+			&e.Method, &e.Path, &e.Status, &e.IP, &e.UserAgent, &e.RequestID,
+			&details, &before, &after); err != nil {
+			return nil, err
+		}
+		e.Details, e.Before, e.After = details, before, after
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Count -> hitung total data untuk pagination
+func (r *auditRepository) Count(ctx context.Context, filter model.AuditFilter) (int, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`+auditWhere, auditFilterArgs(filter)...).Scan(&total)
+	return total, err
+}
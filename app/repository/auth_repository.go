@@ -1,25 +1,96 @@
 package repository
 
 import (
-	"tugas5/app/model"
+	"context"
+	"crypto/subtle"
 	"database/sql"
+	"errors"
+	"strings"
+	"tugas5/app/model"
+	"tugas5/utils"
 )
 
-func Login(db *sql.DB, username string, password string) (model.User, error) {
+// ErrInvalidCredentials -> username ditemukan tetapi password tidak cocok
+var ErrInvalidCredentials = errors.New("username atau password salah")
+
+// ErrAccountDisabled -> password benar tetapi akun sudah dinonaktifkan admin
+var ErrAccountDisabled = errors.New("akun dinonaktifkan")
+
+// ErrEmailNotVerified -> akun hasil registrasi mandiri belum konfirmasi email
+var ErrEmailNotVerified = errors.New("email belum diverifikasi")
+
+// dummyPasswordHash -> hash bcrypt (cost default) yang dibandingkan saat username
+// tidak dikenal, supaya waktu respons tidak membocorkan username mana yang ada
+const dummyPasswordHash = "$2a$10$rpCWy.Czvs0Bb49xvSZfce4fBveyEKmmthSh73vvZtuacTFGuK81a"
+
+func Login(ctx context.Context, db *sql.DB, username string, password string) (model.User, error) {
 	var user model.User
-	row := db.QueryRow("SELECT id, username, email, password_hash, role FROM users WHERE username = $1", username)
-	var hashedPassword string
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &hashedPassword, &user.Role)
+	queryCtx, cancel := readCtx(ctx)
+	defer cancel()
+	row := db.QueryRowContext(queryCtx, `
+		SELECT id, username, email, password_hash, role, alumni_id, is_active,
+		       must_change_password, email_verified_at, totp_enabled, ldap_linked, token_version, created_at
+		FROM users WHERE username = $1
+	`, username)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Role,
+		&user.AlumniID, &user.IsActive, &user.MustChangePassword, &user.EmailVerifiedAt, &user.TOTPEnabled, &user.LDAPLinked, &user.TokenVersion, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.CheckPassword(password, dummyPasswordHash)
+		return user, err
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return user, err
-		}	
 		return user, err
 	}
 
-	// Bandingkan password yang diberikan dengan hash yang disimpan
-	if hashedPassword != password {
+	return verifyPassword(user, password, func(hash string) error {
+		return rehashPassword(ctx, db, user.ID, hash)
+	})
+}
+
+// verifyPassword -> cocokkan password dengan hash tersimpan. Baris lama yang
+// masih plaintext dicocokkan sekali, lalu hash bcrypt-nya diserahkan ke rehash
+// supaya login berikutnya lewat CheckPassword.
+func verifyPassword(user model.User, password string, rehash func(hash string) error) (model.User, error) {
+	if isBcryptHash(user.PasswordHash) {
+		if !utils.CheckPassword(password, user.PasswordHash) {
+			return user, ErrInvalidCredentials
+		}
+		return checkActive(user)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(user.PasswordHash), []byte(password)) != 1 {
+		return user, ErrInvalidCredentials
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return user, err
+	}
+	if err := rehash(hash); err != nil {
 		return user, err
 	}
+	return checkActive(user)
+}
+
+// checkActive -> status akun baru dicek setelah password terbukti benar,
+// supaya status akun tidak bocor ke orang yang hanya menebak username
+func checkActive(user model.User) (model.User, error) {
+	if !user.IsActive {
+		return user, ErrAccountDisabled
+	}
+	if user.EmailVerifiedAt == nil {
+		return user, ErrEmailNotVerified
+	}
 	return user, nil
-}
\ No newline at end of file
+}
+
+// isBcryptHash -> hash bcrypt selalu diawali prefix versi $2a$, $2b$ atau $2y$
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func rehashPassword(ctx context.Context, db *sql.DB, userID int, hash string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := db.ExecContext(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2", hash, userID)
+	return err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tugas5/app/model"
+	"tugas5/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestDummyPasswordHashCostsLikeRealHash(t *testing.T) {
+	// Username tidak dikenal harus memakan waktu bcrypt yang sama dengan login biasa
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil {
+		t.Fatalf("dummyPasswordHash bukan hash bcrypt: %v", err)
+	}
+	realHash, _ := utils.HashPassword("password")
+	if realCost, _ := bcrypt.Cost([]byte(realHash)); cost != realCost {
+		t.Errorf("cost dummy hash %d, hash password asli %d", cost, realCost)
+	}
+}
+
+func TestVerifyPasswordLegacyPlaintext(t *testing.T) {
+	now := time.Now()
+	user := model.User{ID: 4, Username: "lama", PasswordHash: "rahasia123", IsActive: true, EmailVerifiedAt: &now}
+
+	var rehashed []string
+	rehash := func(hash string) error {
+		rehashed = append(rehashed, hash)
+		return nil
+	}
+
+	if _, err := verifyPassword(user, "rahasia12", rehash); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("password salah: error %v, seharusnya ErrInvalidCredentials", err)
+	}
+	if _, err := verifyPassword(user, "rahasia1234", rehash); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("password dengan prefix yang sama: error %v, seharusnya ErrInvalidCredentials", err)
+	}
+	if len(rehashed) != 0 {
+		t.Fatalf("password salah tidak boleh di-rehash, terpanggil %d kali", len(rehashed))
+	}
+
+	if _, err := verifyPassword(user, "rahasia123", rehash); err != nil {
+		t.Fatalf("password plaintext benar: %v", err)
+	}
+	if len(rehashed) != 1 {
+		t.Fatalf("password plaintext seharusnya di-rehash sekali, terpanggil %d kali", len(rehashed))
+	}
+	if !isBcryptHash(rehashed[0]) || !utils.CheckPassword("rahasia123", rehashed[0]) {
+		t.Errorf("hasil rehash bukan bcrypt dari password yang benar: %q", rehashed[0])
+	}
+
+	// Login berikutnya memakai hash baru lewat bcrypt, tanpa rehash lagi
+	user.PasswordHash = rehashed[0]
+	if _, err := verifyPassword(user, "rahasia123", rehash); err != nil || len(rehashed) != 1 {
+		t.Errorf("login dengan hash baru: error %v, rehash %d kali", err, len(rehashed))
+	}
+}
+
+func TestVerifyPasswordRehashFailure(t *testing.T) {
+	now := time.Now()
+	user := model.User{ID: 4, PasswordHash: "rahasia123", IsActive: true, EmailVerifiedAt: &now}
+	failure := errors.New("database tidak tersedia")
+	if _, err := verifyPassword(user, "rahasia123", func(string) error { return failure }); !errors.Is(err, failure) {
+		t.Errorf("rehash gagal: error %v, seharusnya diteruskan", err)
+	}
+}
+
+func TestVerifyPasswordChecksStatusAfterPassword(t *testing.T) {
+	hash, _ := utils.HashPassword("rahasia123")
+	user := model.User{ID: 5, PasswordHash: hash, IsActive: false}
+	noRehash := func(string) error {
+		t.Error("hash bcrypt tidak perlu di-rehash")
+		return nil
+	}
+
+	// Status akun tidak bocor ke orang yang salah menebak password
+	if _, err := verifyPassword(user, "salah", noRehash); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("password salah: error %v, seharusnya ErrInvalidCredentials", err)
+	}
+	if _, err := verifyPassword(user, "rahasia123", noRehash); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("akun nonaktif: error %v, seharusnya ErrAccountDisabled", err)
+	}
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"tugas5/app/model"
+	"tugas5/config"
+	"tugas5/utils"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrDirectoryUnavailable -> server LDAP tidak bisa dihubungi atau menolak akun layanan
+var ErrDirectoryUnavailable = errors.New("direktori LDAP tidak bisa dihubungi")
+
+// ErrNoRoleMapping -> password benar tetapi grup user tidak dipetakan ke role mana pun
+var ErrNoRoleMapping = errors.New("grup LDAP user tidak punya role di sistem ini")
+
+// ErrDirectoryNotLinked -> username LDAP sama dengan akun lokal yang tidak dibuat
+// dari direktori dan belum dihubungkan admin
+var ErrDirectoryNotLinked = errors.New("akun lokal dengan username ini belum dihubungkan ke direktori LDAP")
+
+// ErrDirectoryNoEmail -> entri LDAP tidak punya email, akun lokal tidak bisa dibuat
+var ErrDirectoryNoEmail = errors.New("entri LDAP user tidak punya alamat email")
+
+// Authenticator -> sumber kebenaran username/password. Error mengikuti Login:
+// sql.ErrNoRows kalau username tidak dikenal, ErrInvalidCredentials kalau password salah.
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (model.User, error)
+}
+
+type dbAuthenticator struct {
+	db *sql.DB
+}
+
+// NewDBAuthenticator -> akun lokal di tabel users
+func NewDBAuthenticator(db *sql.DB) Authenticator {
+	return &dbAuthenticator{db: db}
+}
+
+func (a *dbAuthenticator) Authenticate(ctx context.Context, username, password string) (model.User, error) {
+	return Login(ctx, a.db, username, password)
+}
+
+type fallbackAuthenticator struct {
+	primary  Authenticator
+	fallback Authenticator
+}
+
+// NewFallbackAuthenticator -> coba primary dulu; fallback hanya dipakai kalau
+// username tidak dikenal primary atau primary sedang tidak bisa dihubungi.
+// Password salah di primary tidak dicoba ulang ke fallback.
+func NewFallbackAuthenticator(primary, fallback Authenticator) Authenticator {
+	return &fallbackAuthenticator{primary: primary, fallback: fallback}
+}
+
+func (a *fallbackAuthenticator) Authenticate(ctx context.Context, username, password string) (model.User, error) {
+	user, err := a.primary.Authenticate(ctx, username, password)
+	if errors.Is(err, ErrDirectoryUnavailable) {
+		log.Println("Login LDAP gagal, memakai akun lokal:", err)
+	}
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrDirectoryUnavailable) {
+		return a.fallback.Authenticate(ctx, username, password)
+	}
+	return user, err
+}
+
+type ldapAuthenticator struct {
+	cfg   config.LDAPConfig
+	users UserRepository
+}
+
+// NewLDAPAuthenticator -> bind ke direktori sebagai user, lalu sinkronkan akun
+// lokalnya (dibuat kalau belum ada) dengan role dari keanggotaan grup
+func NewLDAPAuthenticator(cfg config.LDAPConfig, users UserRepository) Authenticator {
+	return &ldapAuthenticator{cfg: cfg, users: users}
+}
+
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, username, password string) (model.User, error) {
+	// Bind dengan password kosong di LDAP dianggap bind anonim dan selalu berhasil
+	if password == "" {
+		return model.User{}, ErrInvalidCredentials
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return model.User{}, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+	defer conn.Close()
+
+	entry, err := a.findUser(conn, username)
+	if err != nil {
+		return model.User{}, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return model.User{}, ErrInvalidCredentials
+		}
+		return model.User{}, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+
+	role := a.roleFor(entry.GetAttributeValues(a.cfg.GroupAttr))
+	if role == "" {
+		return model.User{}, ErrNoRoleMapping
+	}
+	user, err := a.syncUser(ctx, username, entry.GetAttributeValue(a.cfg.EmailAttr), role)
+	if err != nil {
+		return model.User{}, err
+	}
+	return checkActive(*user)
+}
+
+func (a *ldapAuthenticator) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: a.cfg.Timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		host := a.cfg.URL
+		if u, err := url.Parse(a.cfg.URL); err == nil {
+			host = u.Hostname()
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// findUser -> cari DN user; sql.ErrNoRows kalau username tidak ada di direktori
+func (a *ldapAuthenticator) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", a.cfg.EmailAttr, a.cfg.GroupAttr},
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	// Filter yang cocok ke lebih dari satu entri berarti konfigurasi salah;
+	// jangan menebak entri mana yang dimaksud
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("%w: LDAP_USER_FILTER cocok ke %d entri untuk %q", ErrDirectoryUnavailable, len(result.Entries), username)
+	}
+	return result.Entries[0], nil
+}
+
+// roleFor -> role dari grup pertama di LDAP_GROUP_ROLES yang dimiliki user
+func (a *ldapAuthenticator) roleFor(groups []string) string {
+	for _, mapping := range a.cfg.GroupRoles {
+		for _, group := range groups {
+			if sameDN(mapping.GroupDN, group) {
+				return mapping.Role
+			}
+		}
+	}
+	return a.cfg.DefaultRole
+}
+
+func sameDN(a, b string) bool {
+	dnA, errA := ldap.ParseDN(a)
+	dnB, errB := ldap.ParseDN(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return dnA.EqualFold(dnB)
+}
+
+// syncUser -> direktori adalah sumber role; akun lokal dibuat saat login pertama
+// dengan password acak, jadi tidak bisa dipakai login tanpa LDAP. Akun lokal lain
+// yang kebetulan bernama sama tidak diambil alih kecuali sudah dihubungkan admin.
+func (a *ldapAuthenticator) syncUser(ctx context.Context, username, email, role string) (*model.User, error) {
+	user, err := a.users.GetByUsername(ctx, username)
+	if err == nil {
+		if !user.LDAPLinked {
+			log.Printf("Login LDAP %s ditolak: akun lokal dengan username yang sama belum dihubungkan", username)
+			return nil, ErrDirectoryNotLinked
+		}
+		if user.Role != role {
+			if err := a.users.UpdateRole(ctx, user.ID, role); err != nil {
+				return nil, err
+			}
+			log.Printf("Role user %s disinkronkan dari LDAP: %s -> %s", username, user.Role, role)
+			// Baca ulang supaya token_version yang baru ikut masuk ke token
+			return a.users.GetByID(ctx, user.ID)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if email == "" {
+		return nil, ErrDirectoryNoEmail
+	}
+
+	password, err := utils.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user, err = a.users.Create(ctx, model.CreateUserRequest{
+		Username: username,
+		Email:    email,
+		Role:     role,
+	}, hash, true)
+	if errors.Is(err, ErrUserConflict) {
+		// Email sudah dipakai akun lokal lain
+		return nil, ErrDirectoryNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := a.users.SetLDAPLinked(ctx, user.ID, true); err != nil {
+		return nil, err
+	}
+	user.LDAPLinked = true
+	log.Printf("User %s dibuat dari direktori LDAP dengan role %s", username, role)
+	return user, nil
+}
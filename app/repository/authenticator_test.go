@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tugas5/app/model"
+	"tugas5/config"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// testDirectory -> server LDAPv3 minimal di proses yang sama: hanya bind simple
+// dan search dengan filter (uid=...), cukup untuk ldapAuthenticator
+type testDirectory struct {
+	listener net.Listener
+	entries  []testEntry
+}
+
+type testEntry struct {
+	dn       string
+	uid      string
+	password string
+	attrs    map[string][]string
+}
+
+var uidFilter = regexp.MustCompile(`\(uid=([^)]*)\)`)
+
+const (
+	ldapBindRequest        = 0
+	ldapBindResponse       = 1
+	ldapUnbindRequest      = 2
+	ldapSearchRequest      = 3
+	ldapSearchResultEntry  = 4
+	ldapSearchResultDone   = 5
+	ldapResultSuccess      = 0
+	ldapResultInvalidCreds = 49
+)
+
+func startTestDirectory(t *testing.T, entries ...testEntry) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &testDirectory{listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+	go d.serve()
+	return "ldap://" + listener.Addr().String()
+}
+
+func (d *testDirectory) serve() {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *testDirectory) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		packet, err := ber.ReadPacket(reader)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldapBindRequest:
+			dn, _ := op.Children[1].Value.(string)
+			code := ldapResultSuccess
+			if dn != "" && !d.checkPassword(dn, op.Children[2].Data.String()) {
+				code = ldapResultInvalidCreds
+			}
+			conn.Write(ldapMessage(id, ldapResult(ldapBindResponse, code)).Bytes())
+		case ldapSearchRequest:
+			filter, err := ldap.DecompileFilter(op.Children[6])
+			if err != nil {
+				return
+			}
+			for _, entry := range d.search(filter) {
+				conn.Write(ldapMessage(id, entry).Bytes())
+			}
+			conn.Write(ldapMessage(id, ldapResult(ldapSearchResultDone, ldapResultSuccess)).Bytes())
+		case ldapUnbindRequest:
+			return
+		}
+	}
+}
+
+func (d *testDirectory) checkPassword(dn, password string) bool {
+	for _, e := range d.entries {
+		if e.dn == dn {
+			return e.password == password
+		}
+	}
+	return false
+}
+
+func (d *testDirectory) search(filter string) []*ber.Packet {
+	match := uidFilter.FindStringSubmatch(filter)
+	if match == nil {
+		return nil
+	}
+	var found []*ber.Packet
+	for _, e := range d.entries {
+		if e.uid != match[1] {
+			continue
+		}
+		entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchResultEntry, nil, "")
+		entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, ""))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		for name, values := range e.attrs {
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		entry.AppendChild(attrs)
+		found = append(found, entry)
+	}
+	return found
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	msg.AppendChild(op)
+	return msg
+}
+
+func ldapResult(tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return op
+}
+
+// fakeUsers -> tabel users di memori, hanya method yang dipakai ldapAuthenticator
+type fakeUsers struct {
+	UserRepository
+	mu    sync.Mutex
+	users map[int]*model.User
+}
+
+func (f *fakeUsers) add(u model.User) *model.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.users == nil {
+		f.users = map[int]*model.User{}
+	}
+	u.ID = len(f.users) + 1
+	u.IsActive = true
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	f.users[u.ID] = &u
+	return &u
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copy := *u
+	return &copy, nil
+}
+
+func (f *fakeUsers) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Username == username {
+			copy := *u
+			return &copy, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeUsers) Create(ctx context.Context, req model.CreateUserRequest, passwordHash string, verified bool) (*model.User, error) {
+	f.mu.Lock()
+	for _, u := range f.users {
+		if u.Username == req.Username || strings.EqualFold(u.Email, req.Email) {
+			f.mu.Unlock()
+			return nil, ErrUserConflict
+		}
+	}
+	f.mu.Unlock()
+	return f.add(model.User{Username: req.Username, Email: req.Email, Role: req.Role}), nil
+}
+
+func (f *fakeUsers) UpdateRole(ctx context.Context, id int, role string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users[id].Role = role
+	f.users[id].TokenVersion++
+	return nil
+}
+
+func (f *fakeUsers) SetLDAPLinked(ctx context.Context, id int, linked bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users[id].LDAPLinked = linked
+	return nil
+}
+
+const (
+	testBaseDN     = "ou=people,dc=kampus,dc=ac,dc=id"
+	testAdminGroup = "cn=admin-alumni,ou=groups,dc=kampus,dc=ac,dc=id"
+	testStaffGroup = "cn=staf,ou=groups,dc=kampus,dc=ac,dc=id"
+)
+
+func newTestLDAPAuthenticator(t *testing.T, users UserRepository) Authenticator {
+	t.Helper()
+	person := func(uid, password, mail, group string) testEntry {
+		attrs := map[string][]string{"memberOf": {group}}
+		if mail != "" {
+			attrs["mail"] = []string{mail}
+		}
+		return testEntry{dn: "uid=" + uid + "," + testBaseDN, uid: uid, password: password, attrs: attrs}
+	}
+	url := startTestDirectory(t,
+		person("budi", "rahasia-ldap", "budi@kampus.ac.id", testStaffGroup),
+		person("admin", "admin-ldap", "admin@kampus.ac.id", testStaffGroup),
+		person("tanpa-email", "rahasia-ldap", "", testStaffGroup),
+		person("siti", "rahasia-ldap", "siti@kampus.ac.id", testAdminGroup),
+		// Dua entri dengan uid sama: LDAP_USER_FILTER terlalu longgar
+		person("ganda", "rahasia-ldap", "ganda@kampus.ac.id", testStaffGroup),
+		testEntry{dn: "uid=ganda,ou=alumni,dc=kampus,dc=ac,dc=id", uid: "ganda", password: "x", attrs: map[string][]string{}},
+	)
+	return NewLDAPAuthenticator(config.LDAPConfig{
+		URL:        url,
+		BaseDN:     testBaseDN,
+		UserFilter: "(&(objectClass=person)(uid=%s))",
+		EmailAttr:  "mail",
+		GroupAttr:  "memberOf",
+		GroupRoles: []config.LDAPGroupRole{
+			{GroupDN: testAdminGroup, Role: "admin"},
+			{GroupDN: testStaffGroup, Role: "user"},
+		},
+		Timeout: 5 * time.Second,
+	}, users)
+}
+
+func TestLDAPAuthenticatorCreatesLinkedUser(t *testing.T) {
+	users := &fakeUsers{}
+	auth := newTestLDAPAuthenticator(t, users)
+
+	user, err := auth.Authenticate(context.Background(), "budi", "rahasia-ldap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "budi@kampus.ac.id" || user.Role != "user" || !user.LDAPLinked {
+		t.Errorf("akun dari direktori tidak sesuai: %+v", user)
+	}
+
+	// Login berikutnya memakai akun yang sama
+	again, err := auth.Authenticate(context.Background(), "budi", "rahasia-ldap")
+	if err != nil || again.ID != user.ID {
+		t.Errorf("login kedua: user %d, err %v; seharusnya user %d", again.ID, err, user.ID)
+	}
+}
+
+func TestLDAPAuthenticatorErrors(t *testing.T) {
+	users := &fakeUsers{}
+	auth := newTestLDAPAuthenticator(t, users)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"password salah", "budi", "salah", ErrInvalidCredentials},
+		{"password kosong", "budi", "", ErrInvalidCredentials},
+		{"tidak ada di direktori", "tidak-ada", "rahasia-ldap", sql.ErrNoRows},
+		{"entri tanpa email", "tanpa-email", "rahasia-ldap", ErrDirectoryNoEmail},
+		{"filter cocok ke dua entri", "ganda", "rahasia-ldap", ErrDirectoryUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.Authenticate(ctx, tt.username, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, seharusnya %v", err, tt.want)
+			}
+		})
+	}
+	if len(users.users) != 0 {
+		t.Errorf("login gagal tidak boleh membuat akun, ada %d", len(users.users))
+	}
+}
+
+func TestLDAPAuthenticatorDoesNotTakeOverLocalUser(t *testing.T) {
+	users := &fakeUsers{}
+	auth := newTestLDAPAuthenticator(t, users)
+	ctx := context.Background()
+	local := users.add(model.User{Username: "admin", Email: "admin@localhost", Role: "admin"})
+	users.add(model.User{Username: "lain", Email: "siti@kampus.ac.id", Role: "user"})
+
+	if _, err := auth.Authenticate(ctx, "admin", "admin-ldap"); !errors.Is(err, ErrDirectoryNotLinked) {
+		t.Fatalf("akun lokal belum dihubungkan: err = %v, seharusnya %v", err, ErrDirectoryNotLinked)
+	}
+	if u, _ := users.GetByID(ctx, local.ID); u.Role != "admin" {
+		t.Errorf("role akun lokal tidak boleh diubah direktori, sekarang %q", u.Role)
+	}
+
+	// Email direktori sudah dipakai akun lokal lain
+	if _, err := auth.Authenticate(ctx, "siti", "rahasia-ldap"); !errors.Is(err, ErrDirectoryNotLinked) {
+		t.Errorf("email bentrok: err = %v, seharusnya %v", err, ErrDirectoryNotLinked)
+	}
+
+	// Setelah dihubungkan admin, direktori menjadi sumber role
+	if err := users.SetLDAPLinked(ctx, local.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	user, err := auth.Authenticate(ctx, "admin", "admin-ldap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != local.ID || user.Role != "user" || user.TokenVersion != 1 {
+		t.Errorf("akun terhubung seharusnya disinkronkan: %+v", user)
+	}
+}
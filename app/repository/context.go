@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Batas waktu per operasi database; context dari request tetap berlaku kalau
+// deadline-nya lebih cepat. Diatur sekali saat startup lewat SetQueryTimeouts.
+var (
+	readTimeout  = 5 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+// SetQueryTimeouts -> nilai 0 membiarkan default
+func SetQueryTimeouts(read, write time.Duration) {
+	if read > 0 {
+		readTimeout = read
+	}
+	if write > 0 {
+		writeTimeout = write
+	}
+}
+
+// readCtx -> deadline untuk SELECT
+func readCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, readTimeout)
+}
+
+// writeCtx -> deadline untuk INSERT/UPDATE/DELETE dan transaksi
+func writeCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, writeTimeout)
+}
+
+// IsTimeout -> query dihentikan karena melewati deadline. lib/pq kadang
+// mengembalikan error Postgres query_canceled (57014) alih-alih error context.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// IsCanceled -> context request dibatalkan sebelum query selesai (klien memutus
+// koneksi atau server shutdown)
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
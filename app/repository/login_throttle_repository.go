@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"tugas5/app/model"
+
+	"github.com/lib/pq"
+)
+
+type LoginThrottleRepository interface {
+	LockedUntil(ctx context.Context, keys []string) (*time.Time, error)
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) (bool, error)
+	ListLocked(ctx context.Context) ([]model.LoginLock, error)
+}
+
+type loginThrottleRepository struct {
+	db *sql.DB
+}
+
+func NewLoginThrottleRepository(db *sql.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{db: db}
+}
+
+// LockedUntil -> waktu buka kunci paling akhir dari key yang sedang dikunci, nil kalau tidak ada
+func (r *loginThrottleRepository) LockedUntil(ctx context.Context, keys []string) (*time.Time, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var until *time.Time
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MAX(locked_until) FROM login_throttle
+		WHERE key = ANY($1) AND locked_until > NOW()
+	`, pq.Array(keys)).Scan(&until)
+	return until, err
+}
+
+// RecordFailure -> tambah hitungan gagal; hitungan mulai dari 1 lagi kalau
+// kegagalan terakhir sudah lebih lama dari window
+func (r *loginThrottleRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	var failures int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO login_throttle (key, failures, last_failure_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttle.last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+				ELSE login_throttle.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING failures
+	`, key, window.Seconds()).Scan(&failures)
+	return failures, err
+}
+
+func (r *loginThrottleRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `UPDATE login_throttle SET locked_until = $2 WHERE key = $1`, key, until)
+	return err
+}
+
+// Reset -> hapus hitungan dan kunci; true kalau key tersebut memang sedang dikunci
+func (r *loginThrottleRepository) Reset(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	var lockedUntil *time.Time
+	err := r.db.QueryRowContext(ctx, `DELETE FROM login_throttle WHERE key = $1 RETURNING locked_until`, key).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return lockedUntil != nil && lockedUntil.After(time.Now()), nil
+}
+
+func (r *loginThrottleRepository) ListLocked(ctx context.Context) ([]model.LoginLock, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key, failures, last_failure_at, locked_until
+		FROM login_throttle
+		WHERE locked_until > NOW()
+		ORDER BY locked_until DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locks []model.LoginLock
+	for rows.Next() {
+		var l model.LoginLock
+		if err := rows.Scan(&l.Key, &l.Failures, &l.LastFailureAt, &l.LockedUntil); err != nil {
+			return nil, err
+		}
+		locks = append(locks, l)
+	}
+	return locks, rows.Err()
+}
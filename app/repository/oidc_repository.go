@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// OIDCLoginState -> data yang harus dicocokkan lagi saat IdP memanggil callback
+type OIDCLoginState struct {
+	Nonce        string
+	CodeVerifier string
+}
+
+type OIDCStateRepository interface {
+	Save(ctx context.Context, state string, data OIDCLoginState, expiresAt time.Time) error
+	Consume(ctx context.Context, state string) (*OIDCLoginState, error)
+}
+
+type oidcStateRepository struct {
+	db *sql.DB
+}
+
+func NewOIDCStateRepository(db *sql.DB) OIDCStateRepository {
+	return &oidcStateRepository{db: db}
+}
+
+// Save -> sekalian bersihkan state kedaluwarsa dari login yang tidak diselesaikan
+func (r *oidcStateRepository) Save(ctx context.Context, state string, data OIDCLoginState, expiresAt time.Time) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oidc_login_states (state, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, state, data.Nonce, data.CodeVerifier, expiresAt)
+	return err
+}
+
+// Consume -> state hanya bisa dipakai sekali; ErrNoRows kalau tidak ada atau kedaluwarsa
+func (r *oidcStateRepository) Consume(ctx context.Context, state string) (*OIDCLoginState, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	var data OIDCLoginState
+	err := r.db.QueryRowContext(ctx, `
+		DELETE FROM oidc_login_states
+		WHERE state = $1 AND expires_at > NOW()
+		RETURNING nonce, code_verifier
+	`, state).Scan(&data.Nonce, &data.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	Consume(ctx context.Context, tokenHash string) (int, error)
+}
+
+type passwordResetRepository struct {
+	db DBTX
+}
+
+func NewPasswordResetRepository(db DBTX) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// Create -> simpan token baru; token lama user yang belum dipakai ikut dibatalkan
+func (r *passwordResetRepository) Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	return err
+}
+
+// Consume -> tandai token terpakai dan kembalikan user_id pemiliknya.
+// sql.ErrNoRows kalau token tidak dikenal, sudah dipakai, atau expired.
+func (r *passwordResetRepository) Consume(ctx context.Context, tokenHash string) (int, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	var userID int
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	return userID, err
+}
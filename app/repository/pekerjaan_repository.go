@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -8,29 +9,33 @@ import (
 )
 
 type PekerjaanRepository interface {
-    GetAll(search, sortBy, order string, limit, offset int) ([]model.Pekerjaan, error)
-    GetByID(id int) (*model.Pekerjaan, error)
-    GetByIDFromTrash(id int) (*model.Pekerjaan, error) // <- tambahkan ini
-    GetByAlumniID(alumniID int) ([]model.Pekerjaan, error)
-    Create(req model.CreatePekerjaanRequest) (*model.Pekerjaan, error)
-    Update(id int, req model.UpdatePekerjaanRequest) (*model.Pekerjaan, error)
-    Delete(id int) error
-    GetTrash(role string, username string) ([]model.Pekerjaan, error)
-    Restore(id int) error
-    HardDelete(id int) error
-    GetDeletedInfo(id int) (string, bool, error)
+	GetAll(ctx context.Context, search, sortBy, order string, limit, offset int) ([]model.Pekerjaan, error)
+	GetByID(ctx context.Context, id int) (*model.Pekerjaan, error)
+	GetByIDFromTrash(ctx context.Context, id int) (*model.Pekerjaan, error)
+	GetByIDForUpdate(ctx context.Context, id int) (*model.Pekerjaan, error)
+	GetByAlumniID(ctx context.Context, alumniID int) ([]model.Pekerjaan, error)
+	GetByAlumniIDWithTrash(ctx context.Context, alumniID int) ([]model.Pekerjaan, error)
+	Create(ctx context.Context, req model.CreatePekerjaanRequest) (*model.Pekerjaan, error)
+	Update(ctx context.Context, id int, req model.UpdatePekerjaanRequest) (*model.Pekerjaan, error)
+	Delete(ctx context.Context, id int) error
+	GetTrash(ctx context.Context, all bool, alumniID int) ([]model.Pekerjaan, error)
+	Restore(ctx context.Context, id int) error
+	HardDelete(ctx context.Context, id int) error
+	GetDeletedInfo(ctx context.Context, id int) (string, bool, error)
 }
 
 type pekerjaanRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewPekerjaanRepository(db *sql.DB) PekerjaanRepository {
+func NewPekerjaanRepository(db DBTX) PekerjaanRepository {
 	return &pekerjaanRepository{db: db}
 }
 
 // GetAll dengan pagination, search, dan sorting
-func (r *pekerjaanRepository) GetAll(search, sortBy, order string, limit, offset int) ([]model.Pekerjaan, error) {
+func (r *pekerjaanRepository) GetAll(ctx context.Context, search, sortBy, order string, limit, offset int) ([]model.Pekerjaan, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
 	query := fmt.Sprintf(`
 		SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, lokasi_kerja, 
 		       gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja, status_pekerjaan, 
@@ -41,7 +46,7 @@ func (r *pekerjaanRepository) GetAll(search, sortBy, order string, limit, offset
 		LIMIT $2 OFFSET $3
 	`, sortBy, order)
 
-	rows, err := r.db.Query(query, "%"+search+"%", limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, "%"+search+"%", limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -62,9 +67,11 @@ func (r *pekerjaanRepository) GetAll(search, sortBy, order string, limit, offset
 	return pekerjaanList, nil
 }
 
-func (r *pekerjaanRepository) GetByID(id int) (*model.Pekerjaan, error) {
+func (r *pekerjaanRepository) GetByID(ctx context.Context, id int) (*model.Pekerjaan, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
 	var p model.Pekerjaan
-	row := r.db.QueryRow(`
+	row := r.db.QueryRowContext(ctx, `
 		SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, lokasi_kerja, 
 		       gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja, status_pekerjaan, 
 		       deskripsi_pekerjaan, created_at, updated_at, is_deleted, created_by
@@ -83,8 +90,36 @@ func (r *pekerjaanRepository) GetByID(id int) (*model.Pekerjaan, error) {
 	return &p, nil
 }
 
-func (r *pekerjaanRepository) GetByAlumniID(alumniID int) ([]model.Pekerjaan, error) {
-	rows, err := r.db.Query(`
+// GetByIDForUpdate -> pekerjaan aktif maupun di trash (lihat IsDeleted), barisnya
+// dikunci sampai transaksi selesai supaya snapshot audit sama dengan data yang diubah
+func (r *pekerjaanRepository) GetByIDForUpdate(ctx context.Context, id int) (*model.Pekerjaan, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var p model.Pekerjaan
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, lokasi_kerja, 
+		       gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja, status_pekerjaan, 
+		       deskripsi_pekerjaan, created_at, updated_at, is_deleted, created_by
+		FROM pekerjaan
+		WHERE id = $1
+		FOR UPDATE
+	`, id)
+
+	err := row.Scan(
+		&p.ID, &p.AlumniID, &p.NamaPerusahaan, &p.PosisiJabatan, &p.BidangIndustri,
+		&p.LokasiKerja, &p.GajiRange, &p.TanggalMulaiKerja, &p.TanggalSelesaiKerja,
+		&p.StatusPekerjaan, &p.DeskripsiPekerjaan, &p.CreatedAt, &p.UpdatedAt, &p.IsDeleted, &p.CreatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *pekerjaanRepository) GetByAlumniID(ctx context.Context, alumniID int) ([]model.Pekerjaan, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, lokasi_kerja, 
 		       gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja, status_pekerjaan, 
 		       deskripsi_pekerjaan, created_at, updated_at, is_deleted, created_by
@@ -112,7 +147,41 @@ func (r *pekerjaanRepository) GetByAlumniID(alumniID int) ([]model.Pekerjaan, er
 	return pekerjaanList, nil
 }
 
-func (r *pekerjaanRepository) Create(req model.CreatePekerjaanRequest) (*model.Pekerjaan, error) {
+// GetByAlumniIDWithTrash -> semua pekerjaan alumni termasuk yang di trash
+func (r *pekerjaanRepository) GetByAlumniIDWithTrash(ctx context.Context, alumniID int) ([]model.Pekerjaan, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, lokasi_kerja,
+		       gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja, status_pekerjaan,
+		       deskripsi_pekerjaan, created_at, updated_at, is_deleted, created_by
+		FROM pekerjaan
+		WHERE alumni_id = $1
+		ORDER BY id
+	`, alumniID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pekerjaanList []model.Pekerjaan
+	for rows.Next() {
+		var p model.Pekerjaan
+		if err := rows.Scan(
+			&p.ID, &p.AlumniID, &p.NamaPerusahaan, &p.PosisiJabatan, &p.BidangIndustri,
+			&p.LokasiKerja, &p.GajiRange, &p.TanggalMulaiKerja, &p.TanggalSelesaiKerja,
+			&p.StatusPekerjaan, &p.DeskripsiPekerjaan, &p.CreatedAt, &p.UpdatedAt, &p.IsDeleted, &p.CreatedBy,
+		); err != nil {
+			return nil, err
+		}
+		pekerjaanList = append(pekerjaanList, p)
+	}
+	return pekerjaanList, rows.Err()
+}
+
+func (r *pekerjaanRepository) Create(ctx context.Context, req model.CreatePekerjaanRequest) (*model.Pekerjaan, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
 	var id int
 	var tanggalMulai, tanggalSelesai *time.Time
 
@@ -132,7 +201,7 @@ func (r *pekerjaanRepository) Create(req model.CreatePekerjaanRequest) (*model.P
 		tanggalSelesai = &t
 	}
 
-	err := r.db.QueryRow(`
+	err := r.db.QueryRowContext(ctx, `
 		INSERT INTO pekerjaan (alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri,
 							   lokasi_kerja, gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja,
 							   status_pekerjaan, deskripsi_pekerjaan, created_at, updated_at, is_deleted, created_by)
@@ -146,10 +215,12 @@ func (r *pekerjaanRepository) Create(req model.CreatePekerjaanRequest) (*model.P
 		return nil, err
 	}
 
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
 }
 
-func (r *pekerjaanRepository) Update(id int, req model.UpdatePekerjaanRequest) (*model.Pekerjaan, error) {
+func (r *pekerjaanRepository) Update(ctx context.Context, id int, req model.UpdatePekerjaanRequest) (*model.Pekerjaan, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
 	var tanggalMulai, tanggalSelesai *time.Time
 
 	if req.TanggalMulaiKerja != "" {
@@ -168,7 +239,7 @@ func (r *pekerjaanRepository) Update(id int, req model.UpdatePekerjaanRequest) (
 		tanggalSelesai = &t
 	}
 
-	result, err := r.db.Exec(`
+	result, err := r.db.ExecContext(ctx, `
 		UPDATE pekerjaan
 		SET nama_perusahaan = $1, posisi_jabatan = $2, bidang_industri = $3, lokasi_kerja = $4,
 			gaji_range = $5, tanggal_mulai_kerja = $6, tanggal_selesai_kerja = $7, 
@@ -187,11 +258,13 @@ func (r *pekerjaanRepository) Update(id int, req model.UpdatePekerjaanRequest) (
 		return nil, sql.ErrNoRows
 	}
 
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
 }
 
-func (r *pekerjaanRepository) Delete(id int) error {
-	result, err := r.db.Exec("UPDATE pekerjaan SET is_deleted = true, updated_at = $2 WHERE id = $1", id, time.Now())
+func (r *pekerjaanRepository) Delete(ctx context.Context, id int) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, "UPDATE pekerjaan SET is_deleted = true, updated_at = $2 WHERE id = $1", id, time.Now())
 	if err != nil {
 		return err
 	}
@@ -203,8 +276,10 @@ func (r *pekerjaanRepository) Delete(id int) error {
 }
 
 // ✅ Perbaikan receiver function GetDeletedInfo
-func (r *pekerjaanRepository) GetDeletedInfo(id int) (string, bool, error) {
-	row := r.db.QueryRow(`SELECT created_by, is_deleted FROM pekerjaan WHERE id=$1`, id)
+func (r *pekerjaanRepository) GetDeletedInfo(ctx context.Context, id int) (string, bool, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	row := r.db.QueryRowContext(ctx, `SELECT created_by, is_deleted FROM pekerjaan WHERE id=$1`, id)
 
 	var createdBy sql.NullString
 	var isDeleted bool
@@ -218,62 +293,71 @@ func (r *pekerjaanRepository) GetDeletedInfo(id int) (string, bool, error) {
 	return createdBy.String, isDeleted, nil
 }
 
-func (r *pekerjaanRepository) GetTrash(role string, username string) ([]model.Pekerjaan, error) {
-    var rows *sql.Rows
-    var err error
+// GetTrash -> all=true untuk seluruh trash, selain itu hanya milik alumniID
+func (r *pekerjaanRepository) GetTrash(ctx context.Context, all bool, alumniID int) ([]model.Pekerjaan, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var rows *sql.Rows
+	var err error
 
-    if role == "admin" {
-        rows, err = r.db.Query(`
+	if all {
+		rows, err = r.db.QueryContext(ctx, `
             SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, 
                    lokasi_kerja, gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja,
                    status_pekerjaan, deskripsi_pekerjaan, created_at, updated_at, 
                    is_deleted, created_by
             FROM pekerjaan WHERE is_deleted = TRUE
         `)
-    } else {
-        rows, err = r.db.Query(`
+	} else {
+		rows, err = r.db.QueryContext(ctx, `
             SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, 
                    lokasi_kerja, gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja,
                    status_pekerjaan, deskripsi_pekerjaan, created_at, updated_at, 
                    is_deleted, created_by
-            FROM pekerjaan WHERE is_deleted = TRUE AND created_by = $1
-        `, username)
-    }
-
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var data []model.Pekerjaan
-    for rows.Next() {
-        var p model.Pekerjaan
-        err := rows.Scan(
-            &p.ID, &p.AlumniID, &p.NamaPerusahaan, &p.PosisiJabatan, &p.BidangIndustri,
-            &p.LokasiKerja, &p.GajiRange, &p.TanggalMulaiKerja, &p.TanggalSelesaiKerja,
-            &p.StatusPekerjaan, &p.DeskripsiPekerjaan, &p.CreatedAt, &p.UpdatedAt,
-            &p.IsDeleted, &p.CreatedBy,
-        )
-        if err != nil {
-            return nil, err
-        }
-        data = append(data, p)
-    }
-    return data, nil
+            FROM pekerjaan WHERE is_deleted = TRUE AND alumni_id = $1
+        `, alumniID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.Pekerjaan
+	for rows.Next() {
+		var p model.Pekerjaan
+		err := rows.Scan(
+			&p.ID, &p.AlumniID, &p.NamaPerusahaan, &p.PosisiJabatan, &p.BidangIndustri,
+			&p.LokasiKerja, &p.GajiRange, &p.TanggalMulaiKerja, &p.TanggalSelesaiKerja,
+			&p.StatusPekerjaan, &p.DeskripsiPekerjaan, &p.CreatedAt, &p.UpdatedAt,
+			&p.IsDeleted, &p.CreatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, p)
+	}
+	return data, nil
 }
 
-func (r *pekerjaanRepository) Restore(id int) error {
-	_, err := r.db.Exec("UPDATE pekerjaan SET is_deleted = FALSE WHERE id = $1", id)
+func (r *pekerjaanRepository) Restore(ctx context.Context, id int) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, "UPDATE pekerjaan SET is_deleted = FALSE WHERE id = $1", id)
 	return err
 }
 
-func (r *pekerjaanRepository) HardDelete(id int) error {
-	_, err := r.db.Exec("DELETE FROM pekerjaan WHERE id = $1", id)
+func (r *pekerjaanRepository) HardDelete(ctx context.Context, id int) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, "DELETE FROM pekerjaan WHERE id = $1", id)
 	return err
 }
 
-func (r *pekerjaanRepository) GetByIDFromTrash(id int) (*model.Pekerjaan, error) {
-    row := r.db.QueryRow(`
+func (r *pekerjaanRepository) GetByIDFromTrash(ctx context.Context, id int) (*model.Pekerjaan, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	row := r.db.QueryRowContext(ctx, `
         SELECT id, alumni_id, nama_perusahaan, posisi_jabatan, bidang_industri, 
                lokasi_kerja, gaji_range, tanggal_mulai_kerja, tanggal_selesai_kerja,
                status_pekerjaan, deskripsi_pekerjaan, created_at, updated_at, 
@@ -281,15 +365,15 @@ func (r *pekerjaanRepository) GetByIDFromTrash(id int) (*model.Pekerjaan, error)
         FROM pekerjaan WHERE id = $1 AND is_deleted = TRUE
     `, id)
 
-    var p model.Pekerjaan
-    if err := row.Scan(
-        &p.ID, &p.AlumniID, &p.NamaPerusahaan, &p.PosisiJabatan, &p.BidangIndustri,
-        &p.LokasiKerja, &p.GajiRange, &p.TanggalMulaiKerja, &p.TanggalSelesaiKerja,
-        &p.StatusPekerjaan, &p.DeskripsiPekerjaan, &p.CreatedAt, &p.UpdatedAt,
-        &p.IsDeleted, &p.CreatedBy,
-    ); err != nil {
-        return nil, err
-    }
-
-    return &p, nil
-}
\ No newline at end of file
+	var p model.Pekerjaan
+	if err := row.Scan(
+		&p.ID, &p.AlumniID, &p.NamaPerusahaan, &p.PosisiJabatan, &p.BidangIndustri,
+		&p.LokasiKerja, &p.GajiRange, &p.TanggalMulaiKerja, &p.TanggalSelesaiKerja,
+		&p.StatusPekerjaan, &p.DeskripsiPekerjaan, &p.CreatedAt, &p.UpdatedAt,
+		&p.IsDeleted, &p.CreatedBy,
+	); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
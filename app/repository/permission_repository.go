@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type PermissionRepository interface {
+	GetByRole(ctx context.Context, role string) ([]string, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+	ListAll(ctx context.Context) ([]string, error)
+}
+
+type permissionRepository struct {
+	db *sql.DB
+}
+
+func NewPermissionRepository(db *sql.DB) PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) GetByRole(ctx context.Context, role string) ([]string, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `SELECT permission FROM role_permissions WHERE role = $1`, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
+
+func (r *permissionRepository) RoleExists(ctx context.Context, role string) (bool, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1)`, role).Scan(&exists)
+	return exists, err
+}
+
+// ListAll -> semua nama permission yang terdaftar
+func (r *permissionRepository) ListAll(ctx context.Context) ([]string, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `SELECT name FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"tugas5/app/model"
+)
+
+type RevisionRepository interface {
+	// Record -> simpan rev sebagai revisi berikutnya; kalau data ini belum punya
+	// revisi sama sekali, baseline (kalau ada) disimpan dulu sebagai revisi 1
+	Record(ctx context.Context, rev *model.Revision, baseline *model.Revision) error
+	List(ctx context.Context, entityType string, entityID int) ([]model.Revision, error)
+	Get(ctx context.Context, entityType string, entityID, revision int) (*model.Revision, error)
+}
+
+type revisionRepository struct {
+	db DBTX
+}
+
+func NewRevisionRepository(db DBTX) RevisionRepository {
+	return &revisionRepository{db: db}
+}
+
+func (r *revisionRepository) Record(ctx context.Context, rev *model.Revision, baseline *model.Revision) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	return inTx(ctx, r.db, func(tx DBTX) error {
+		// Kunci per data supaya dua perubahan bersamaan tidak berebut nomor revisi
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1), $2)`, rev.EntityType, rev.EntityID); err != nil {
+			return err
+		}
+		var last int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(revision), 0) FROM revisions WHERE entity_type = $1 AND entity_id = $2
+		`, rev.EntityType, rev.EntityID).Scan(&last); err != nil {
+			return err
+		}
+		if last == 0 && baseline != nil {
+			last++
+			if err := insertRevision(ctx, tx, baseline, last); err != nil {
+				return err
+			}
+		}
+		return insertRevision(ctx, tx, rev, last+1)
+	})
+}
+
+func insertRevision(ctx context.Context, tx DBTX, rev *model.Revision, number int) error {
+	rev.Revision = number
+	return tx.QueryRowContext(ctx, `
+		INSERT INTO revisions (entity_type, entity_id, revision, action, data, changed_by_user_id, changed_by, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, rev.EntityType, rev.EntityID, rev.Revision, rev.Action, string(rev.Data),
+		rev.ChangedByUserID, rev.ChangedBy, rev.RequestID).Scan(&rev.ID, &rev.CreatedAt)
+}
+
+const revisionColumns = `id, entity_type, entity_id, revision, action, data, changed_by_user_id, changed_by, request_id, created_at`
+
+func scanRevision(row interface{ Scan(...any) error }) (*model.Revision, error) {
+	var rev model.Revision
+	var data []byte
+	if err := row.Scan(&rev.ID, &rev.EntityType, &rev.EntityID, &rev.Revision, &rev.Action, &data,
+		&rev.ChangedByUserID, &rev.ChangedBy, &rev.RequestID, &rev.CreatedAt); err != nil {
+		return nil, err
+	}
+	rev.Data = data
+	return &rev, nil
+}
+
+// List -> revisi terlama lebih dulu
+func (r *revisionRepository) List(ctx context.Context, entityType string, entityID int) ([]model.Revision, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+revisionColumns+` FROM revisions
+		WHERE entity_type = $1 AND entity_id = $2
+		ORDER BY revision
+	`, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []model.Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *revisionRepository) Get(ctx context.Context, entityType string, entityID, revision int) (*model.Revision, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	return scanRevision(r.db.QueryRowContext(ctx, `
+		SELECT `+revisionColumns+` FROM revisions
+		WHERE entity_type = $1 AND entity_id = $2 AND revision = $3
+	`, entityType, entityID, revision))
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"tugas5/app/model"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session, refreshHash string) error
+	GetByID(ctx context.Context, id string) (*model.Session, error)
+	ListActiveForUser(ctx context.Context, userID int) ([]model.Session, error)
+	GetByRefreshHash(ctx context.Context, hash string) (*model.Session, error)
+	GetByPreviousHash(ctx context.Context, hash string) (*model.Session, error)
+	Rotate(ctx context.Context, id, oldHash, newHash, accessJTI string, accessExpiresAt time.Time, client model.SessionClient) error
+	Touch(ctx context.Context, id string, client model.SessionClient) error
+	Revoke(ctx context.Context, id string) error
+	RevokeAllForUser(ctx context.Context, userID int) error
+	RevokeOthers(ctx context.Context, userID int, keepID string) error
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	ConsumeToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+}
+
+type sessionRepository struct {
+	db DBTX
+}
+
+func NewSessionRepository(db DBTX) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+const sessionColumns = `id, user_id, access_jti, access_expires_at, expires_at, created_at, last_used_at, revoked_at, user_agent, ip, device`
+
+func scanSession(row interface{ Scan(...any) error }) (*model.Session, error) {
+	var s model.Session
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.AccessJTI, &s.AccessExpiresAt, &s.ExpiresAt,
+		&s.CreatedAt, &s.LastUsedAt, &s.RevokedAt, &s.UserAgent, &s.IP, &s.Device,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *model.Session, refreshHash string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO user_sessions (id, user_id, refresh_token_hash, access_jti, access_expires_at, expires_at,
+			user_agent, ip, device)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at, last_used_at
+	`, session.ID, session.UserID, refreshHash, session.AccessJTI, session.AccessExpiresAt, session.ExpiresAt,
+		session.UserAgent, session.IP, session.Device).
+		Scan(&session.CreatedAt, &session.LastUsedAt)
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	return scanSession(r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM user_sessions WHERE id = $1`, id))
+}
+
+// ListActiveForUser -> sesi yang belum dicabut dan refresh token-nya belum expired
+func (r *sessionRepository) ListActiveForUser(ctx context.Context, userID int) ([]model.Session, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+sessionColumns+` FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *sessionRepository) GetByRefreshHash(ctx context.Context, hash string) (*model.Session, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	row := r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM user_sessions WHERE refresh_token_hash = $1`, hash)
+	return scanSession(row)
+}
+
+// GetByPreviousHash -> dipakai untuk mendeteksi refresh token lama yang dipakai ulang
+func (r *sessionRepository) GetByPreviousHash(ctx context.Context, hash string) (*model.Session, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	row := r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM user_sessions WHERE previous_token_hash = $1`, hash)
+	return scanSession(row)
+}
+
+// Rotate -> ganti refresh token sesi; gagal (ErrNoRows) kalau token lama sudah
+// dirotasi oleh request lain atau sesi sudah dicabut
+func (r *sessionRepository) Rotate(ctx context.Context, id, oldHash, newHash, accessJTI string, accessExpiresAt time.Time, client model.SessionClient) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET refresh_token_hash = $1, previous_token_hash = $2, access_jti = $3,
+			access_expires_at = $4, last_used_at = NOW(), user_agent = $6, ip = $7, device = $8
+		WHERE id = $5 AND refresh_token_hash = $2 AND revoked_at IS NULL
+	`, newHash, oldHash, accessJTI, accessExpiresAt, id, client.UserAgent, client.IP, client.Device)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Touch -> perbarui "terakhir dilihat"; paling banyak satu tulis per menit per sesi
+func (r *sessionRepository) Touch(ctx context.Context, id string, client model.SessionClient) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_sessions SET last_used_at = NOW(), ip = $2, user_agent = $3, device = $4
+		WHERE id = $1 AND revoked_at IS NULL AND last_used_at < NOW() - INTERVAL '1 minute'
+	`, id, client.IP, client.UserAgent, client.Device)
+	return err
+}
+
+// Revoke -> cabut satu sesi sekaligus masukkan access token terakhirnya ke denylist
+func (r *sessionRepository) Revoke(ctx context.Context, id string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE user_sessions SET revoked_at = NOW()
+			WHERE id = $1 AND revoked_at IS NULL
+			RETURNING access_jti, access_expires_at
+		)
+		INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM revoked WHERE access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING
+	`, id)
+	return err
+}
+
+// RevokeAllForUser -> cabut semua sesi aktif milik user
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID int) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE user_sessions SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL
+			RETURNING access_jti, access_expires_at
+		)
+		INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM revoked WHERE access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING
+	`, userID)
+	return err
+}
+
+// RevokeOthers -> cabut semua sesi user kecuali sesi yang sedang dipakai
+func (r *sessionRepository) RevokeOthers(ctx context.Context, userID int, keepID string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE user_sessions SET revoked_at = NOW()
+			WHERE user_id = $1 AND id::text <> $2 AND revoked_at IS NULL
+			RETURNING access_jti, access_expires_at
+		)
+		INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM revoked WHERE access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING
+	`, userID, keepID)
+	return err
+}
+
+func (r *sessionRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, expiresAt); err != nil {
+		return err
+	}
+	// Entri yang token-nya sudah expired tidak perlu disimpan lagi
+	_, err := r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < NOW()`)
+	return err
+}
+
+func (r *sessionRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
+
+// ConsumeToken -> tandai token sekali pakai sebagai terpakai; false kalau jti
+// itu sudah pernah dicatat sebelumnya
+func (r *sessionRepository) ConsumeToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"tugas5/app/model"
+)
+
+type TwoFactorRepository interface {
+	GetState(ctx context.Context, userID int) (*model.TwoFactorState, error)
+	SetPendingSecret(ctx context.Context, userID int, secret string) error
+	Enable(ctx context.Context, userID int, step int64, codeHashes []string) error
+	Disable(ctx context.Context, userID int) error
+	MarkStepUsed(ctx context.Context, userID int, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+}
+
+type twoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) GetState(ctx context.Context, userID int) (*model.TwoFactorState, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var s model.TwoFactorState
+	err := r.db.QueryRowContext(ctx, `SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE id = $1`, userID).
+		Scan(&s.Secret, &s.Enabled, &s.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetPendingSecret -> simpan secret baru selama 2FA belum aktif; secret yang
+// sedang aktif tidak pernah ditimpa lewat sini
+func (r *twoFactorRepository) SetPendingSecret(ctx context.Context, userID int, secret string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users SET totp_secret = $1, totp_last_step = 0
+		WHERE id = $2 AND totp_enabled = FALSE
+	`, secret, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) Enable(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users SET totp_enabled = TRUE, totp_last_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled = FALSE
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *twoFactorRepository) Disable(ctx context.Context, userID int) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0
+		WHERE id = $1
+	`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkStepUsed -> false kalau langkah ini (atau yang lebih baru) sudah pernah dipakai
+func (r *twoFactorRepository) MarkStepUsed(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND totp_last_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected == 1, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected == 1, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX -> bagian *sql.DB yang dipakai repository. *sql.Tx juga memenuhinya,
+// jadi repository yang sama bisa dipakai di luar maupun di dalam transaksi.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Repositories -> repository yang bisa digabung dalam satu unit of work
+type Repositories struct {
+	Users          UserRepository
+	Alumni         AlumniRepository
+	Pekerjaan      PekerjaanRepository
+	Audit          AuditRepository
+	Revisions      RevisionRepository
+	Sessions       SessionRepository
+	PasswordResets PasswordResetRepository
+}
+
+func newRepositories(db DBTX) Repositories {
+	return Repositories{
+		Users:          NewUserRepository(db),
+		Alumni:         NewAlumniRepository(db),
+		Pekerjaan:      NewPekerjaanRepository(db),
+		Audit:          NewAuditRepository(db),
+		Revisions:      NewRevisionRepository(db),
+		Sessions:       NewSessionRepository(db),
+		PasswordResets: NewPasswordResetRepository(db),
+	}
+}
+
+// UnitOfWork -> beberapa operasi repository yang harus berhasil atau gagal bersama
+type UnitOfWork interface {
+	// Do -> fn menerima repository yang terikat ke satu transaksi. Transaksi
+	// di-commit kalau fn mengembalikan nil dan di-rollback kalau fn gagal atau panic.
+	Do(ctx context.Context, fn func(tx Repositories) error) error
+}
+
+type unitOfWork struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(tx Repositories) error) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	err := inTx(ctx, u.db, func(tx DBTX) error {
+		return fn(newRepositories(tx))
+	})
+	// Transaksi yang melewati batas waktu di-rollback oleh database/sql dan
+	// query berikutnya hanya mendapat sql.ErrTxDone; kembalikan penyebab aslinya
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// inTx -> jalankan fn dalam transaksi. Repository yang sudah terikat ke
+// transaksi unit of work ikut transaksi itu; commit menjadi urusan pemiliknya.
+func inTx(ctx context.Context, db DBTX, fn func(tx DBTX) error) error {
+	conn, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+	"tugas5/app/model"
+)
+
+// CachedUserRepository -> UserRepository dengan cache singkat untuk pengecekan
+// status user di setiap request. Semua perubahan lewat repository ini langsung
+// membuang entri user yang bersangkutan.
+type CachedUserRepository interface {
+	UserRepository
+	// GetCached -> seperti GetByID, tetapi boleh basi paling lama TTL cache
+	GetCached(ctx context.Context, id int) (*model.User, error)
+	Invalidate(id int)
+}
+
+type cachedUser struct {
+	user     model.User
+	loadedAt time.Time
+}
+
+type cachedUserRepository struct {
+	UserRepository
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[int]cachedUser
+}
+
+func NewCachedUserRepository(repo UserRepository, ttl time.Duration) CachedUserRepository {
+	return &cachedUserRepository{UserRepository: repo, ttl: ttl, entries: map[int]cachedUser{}}
+}
+
+func (r *cachedUserRepository) GetCached(ctx context.Context, id int) (*model.User, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[id]
+	r.mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < r.ttl {
+		user := entry.user
+		return &user, nil
+	}
+
+	user, err := r.UserRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.entries[id] = cachedUser{user: *user, loadedAt: time.Now()}
+	r.mu.Unlock()
+	return user, nil
+}
+
+func (r *cachedUserRepository) Invalidate(id int) {
+	r.mu.Lock()
+	delete(r.entries, id)
+	r.mu.Unlock()
+}
+
+// invalidateAfter -> buang cache setelah perubahan, termasuk kalau perubahan gagal
+// sebagian, supaya request berikutnya membaca ulang dari database
+func (r *cachedUserRepository) invalidateAfter(id int, err error) error {
+	r.Invalidate(id)
+	return err
+}
+
+func (r *cachedUserRepository) SetOIDCSubject(ctx context.Context, id int, subject string) error {
+	return r.invalidateAfter(id, r.UserRepository.SetOIDCSubject(ctx, id, subject))
+}
+
+func (r *cachedUserRepository) MarkEmailVerified(ctx context.Context, id int) error {
+	return r.invalidateAfter(id, r.UserRepository.MarkEmailVerified(ctx, id))
+}
+
+func (r *cachedUserRepository) UpdateEmail(ctx context.Context, id int, email string) error {
+	return r.invalidateAfter(id, r.UserRepository.UpdateEmail(ctx, id, email))
+}
+
+func (r *cachedUserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	return r.invalidateAfter(id, r.UserRepository.UpdatePassword(ctx, id, passwordHash))
+}
+
+func (r *cachedUserRepository) ResetPassword(ctx context.Context, id int, passwordHash string) error {
+	return r.invalidateAfter(id, r.UserRepository.ResetPassword(ctx, id, passwordHash))
+}
+
+func (r *cachedUserRepository) ForcePasswordReset(ctx context.Context, id int, passwordHash string) error {
+	return r.invalidateAfter(id, r.UserRepository.ForcePasswordReset(ctx, id, passwordHash))
+}
+
+func (r *cachedUserRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	return r.invalidateAfter(id, r.UserRepository.UpdateRole(ctx, id, role))
+}
+
+func (r *cachedUserRepository) SetActive(ctx context.Context, id int, active bool) error {
+	return r.invalidateAfter(id, r.UserRepository.SetActive(ctx, id, active))
+}
+
+func (r *cachedUserRepository) BumpTokenVersion(ctx context.Context, id int) error {
+	return r.invalidateAfter(id, r.UserRepository.BumpTokenVersion(ctx, id))
+}
+
+func (r *cachedUserRepository) LinkAlumni(ctx context.Context, userID, alumniID int) error {
+	return r.invalidateAfter(userID, r.UserRepository.LinkAlumni(ctx, userID, alumniID))
+}
+
+func (r *cachedUserRepository) UnlinkAlumni(ctx context.Context, userID int) error {
+	return r.invalidateAfter(userID, r.UserRepository.UnlinkAlumni(ctx, userID))
+}
+
+func (r *cachedUserRepository) SetLDAPLinked(ctx context.Context, id int, linked bool) error {
+	return r.invalidateAfter(id, r.UserRepository.SetLDAPLinked(ctx, id, linked))
+}
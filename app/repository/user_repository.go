@@ -1,50 +1,252 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"tugas5/app/model"
-	"tugas5/database"
+
+	"github.com/lib/pq"
 )
 
-// GetUsersRepo -> ambil data users dari DB
-func GetUsersRepo(search, sortBy, order string, limit, offset int) ([]model.User, error) {
+// ErrAlumniAlreadyLinked -> record alumni sudah terhubung ke akun lain
+var ErrAlumniAlreadyLinked = errors.New("alumni sudah terhubung dengan akun lain")
+
+// ErrEmailTaken -> email sudah dipakai akun lain
+var ErrEmailTaken = errors.New("email sudah dipakai akun lain")
+
+// ErrUserConflict -> username, email, atau alumni sudah dipakai akun lain
+var ErrUserConflict = errors.New("username, email, atau alumni sudah dipakai akun lain")
+
+type UserRepository interface {
+	List(ctx context.Context, search, role, sortBy, order string, limit, offset int) ([]model.User, error)
+	Count(ctx context.Context, search, role string) (int, error)
+	GetByID(ctx context.Context, id int) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByOIDCSubject(ctx context.Context, subject string) (*model.User, error)
+	SetOIDCSubject(ctx context.Context, id int, subject string) error
+	Create(ctx context.Context, req model.CreateUserRequest, passwordHash string, verified bool) (*model.User, error)
+	MarkEmailVerified(ctx context.Context, id int) error
+	GetPasswordHash(ctx context.Context, id int) (string, error)
+	UpdateEmail(ctx context.Context, id int, email string) error
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	ResetPassword(ctx context.Context, id int, passwordHash string) error
+	ForcePasswordReset(ctx context.Context, id int, passwordHash string) error
+	UpdateRole(ctx context.Context, id int, role string) error
+	SetActive(ctx context.Context, id int, active bool) error
+	BumpTokenVersion(ctx context.Context, id int) error
+	LinkAlumni(ctx context.Context, userID, alumniID int) error
+	UnlinkAlumni(ctx context.Context, userID int) error
+	SetLDAPLinked(ctx context.Context, id int, linked bool) error
+}
+
+type userRepository struct {
+	db DBTX
+}
+
+func NewUserRepository(db DBTX) UserRepository {
+	return &userRepository{db: db}
+}
+
+const userColumns = `id, username, email, role, alumni_id, is_active, must_change_password, email_verified_at, totp_enabled, ldap_linked, token_version, created_at`
+
+func scanUser(row interface{ Scan(...any) error }) (*model.User, error) {
+	var u model.User
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.AlumniID, &u.IsActive,
+		&u.MustChangePassword, &u.EmailVerifiedAt, &u.TOTPEnabled, &u.LDAPLinked, &u.TokenVersion, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// List -> ambil data users dengan pagination; role kosong berarti semua role
+func (r *userRepository) List(ctx context.Context, search, role, sortBy, order string, limit, offset int) ([]model.User, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
 	query := fmt.Sprintf(`
-	SELECT id, name, email, created_at
+	SELECT %s
 	FROM users
-	WHERE name ILIKE $1 OR email ILIKE $1
+	WHERE (username ILIKE $1 OR email ILIKE $1) AND ($2 = '' OR role = $2)
 	ORDER BY %s %s
-	LIMIT $2 OFFSET $3
-	`, sortBy, order)
-
-	rows, err := database.DB.Query(query, "%"+search+"%", limit, offset)
+	LIMIT $3 OFFSET $4
+	`, userColumns, sortBy, order)
 
+	rows, err := r.db.QueryContext(ctx, query, "%"+search+"%", role, limit, offset)
 	if err != nil {
-		log.Println("Query error:", err)
 		return nil, err
 	}
-
 	defer rows.Close()
+
 	var users []model.User
 	for rows.Next() {
-		var u model.User
-		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt); err != nil {
+		u, err := scanUser(rows)
+		if err != nil {
 			return nil, err
 		}
-		users = append(users, u)
+		users = append(users, *u)
 	}
-	return users, nil
+	return users, rows.Err()
 }
 
-// CountUsersRepo -> hitung total data untuk pagination
-func CountUsersRepo(search string) (int, error) {
+// Count -> hitung total data untuk pagination
+func (r *userRepository) Count(ctx context.Context, search, role string) (int, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
 	var total int
-	countQuery := `SELECT COUNT(*) FROM users WHERE name ILIKE $1 OR
-email ILIKE $1`
-	err := database.DB.QueryRow(countQuery, "%"+search+"%").Scan(&total)
-	if err != nil && err != sql.ErrNoRows {
-		return 0, err
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users
+		WHERE (username ILIKE $1 OR email ILIKE $1) AND ($2 = '' OR role = $2)
+	`, "%"+search+"%", role).Scan(&total)
+	return total, err
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*model.User, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE LOWER(email) = LOWER($1)`, email))
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE username = $1`, username))
+}
+
+func (r *userRepository) GetByOIDCSubject(ctx context.Context, subject string) (*model.User, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE oidc_subject = $1`, subject))
+}
+
+// SetOIDCSubject -> hubungkan akun ke subject IdP; akun yang sudah terhubung
+// ke subject lain tidak ditimpa (ErrNoRows)
+func (r *userRepository) SetOIDCSubject(ctx context.Context, id int, subject string) error {
+	err := r.execOne(ctx, `UPDATE users SET oidc_subject = $1 WHERE id = $2 AND oidc_subject IS NULL`, subject, id)
+	if isUniqueViolation(err) {
+		return ErrUserConflict
+	}
+	return err
+}
+
+// Create -> verified=false untuk registrasi mandiri yang masih menunggu verifikasi email
+func (r *userRepository) Create(ctx context.Context, req model.CreateUserRequest, passwordHash string, verified bool) (*model.User, error) {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO users (username, email, password_hash, role, alumni_id, email_verified_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $6 THEN NOW() END, NOW())
+		RETURNING `+userColumns,
+		req.Username, req.Email, passwordHash, req.Role, req.AlumniID, verified)
+	user, err := scanUser(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrUserConflict
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id int) error {
+	return r.execOne(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`, id)
+}
+
+func (r *userRepository) GetPasswordHash(ctx context.Context, id int) (string, error) {
+	ctx, cancel := readCtx(ctx)
+	defer cancel()
+	var hash string
+	err := r.db.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE id = $1`, id).Scan(&hash)
+	return hash, err
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, id int, email string) error {
+	err := r.execOne(ctx, `UPDATE users SET email = $1 WHERE id = $2`, email, id)
+	if isUniqueViolation(err) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+// UpdatePassword -> password diganti oleh pemilik akun, kewajiban ganti password selesai
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	return r.execOne(ctx, `UPDATE users SET password_hash = $1, must_change_password = FALSE WHERE id = $2`, passwordHash, id)
+}
+
+// ResetPassword -> password diganti lewat token reset dari email. Kepemilikan
+// email ikut terbukti, dan token akses yang sudah terbit tidak berlaku lagi.
+func (r *userRepository) ResetPassword(ctx context.Context, id int, passwordHash string) error {
+	return r.execOne(ctx, `
+		UPDATE users SET password_hash = $1, must_change_password = FALSE,
+		       email_verified_at = COALESCE(email_verified_at, NOW()), token_version = token_version + 1
+		WHERE id = $2
+	`, passwordHash, id)
+}
+
+// ForcePasswordReset -> password di-set admin, user wajib menggantinya setelah login
+func (r *userRepository) ForcePasswordReset(ctx context.Context, id int, passwordHash string) error {
+	return r.execOne(ctx, `
+		UPDATE users SET password_hash = $1, must_change_password = TRUE, token_version = token_version + 1
+		WHERE id = $2
+	`, passwordHash, id)
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	return r.execOne(ctx, `UPDATE users SET role = $1, token_version = token_version + 1 WHERE id = $2`, role, id)
+}
+
+func (r *userRepository) SetActive(ctx context.Context, id int, active bool) error {
+	return r.execOne(ctx, `UPDATE users SET is_active = $1, token_version = token_version + 1 WHERE id = $2`, active, id)
+}
+
+// BumpTokenVersion -> semua access token yang sudah terbit untuk user ini,
+// termasuk token impersonasi yang tidak punya sesi, tidak berlaku lagi
+func (r *userRepository) BumpTokenVersion(ctx context.Context, id int) error {
+	return r.execOne(ctx, `UPDATE users SET token_version = token_version + 1 WHERE id = $1`, id)
+}
+
+func (r *userRepository) LinkAlumni(ctx context.Context, userID, alumniID int) error {
+	err := r.execOne(ctx, `UPDATE users SET alumni_id = $1 WHERE id = $2`, alumniID, userID)
+	if isUniqueViolation(err) {
+		return ErrAlumniAlreadyLinked
 	}
-	return total, nil
+	return err
+}
+
+func (r *userRepository) UnlinkAlumni(ctx context.Context, userID int) error {
+	return r.execOne(ctx, `UPDATE users SET alumni_id = NULL WHERE id = $1`, userID)
+}
+
+// SetLDAPLinked -> izinkan (atau cabut izin) login lewat LDAP untuk akun ini
+func (r *userRepository) SetLDAPLinked(ctx context.Context, id int, linked bool) error {
+	return r.execOne(ctx, `UPDATE users SET ldap_linked = $1 WHERE id = $2`, linked, id)
+}
+
+// execOne -> jalankan UPDATE untuk satu user, sql.ErrNoRows kalau user tidak ada
+func (r *userRepository) execOne(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := writeCtx(ctx)
+	defer cancel()
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// isUniqueViolation -> error Postgres 23505 (unique_violation)
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
@@ -2,20 +2,26 @@ package services
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 	"tugas5/app/model"
 	"tugas5/app/repository"
+	"tugas5/middleware"
+	"tugas5/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type AlumniService struct {
-	repo repository.AlumniRepository
+	repo    repository.AlumniRepository
+	uow     repository.UnitOfWork
+	changes *ChangeLog
 }
 
-func NewAlumniService(repo repository.AlumniRepository) *AlumniService {
-	return &AlumniService{repo: repo}
+func NewAlumniService(repo repository.AlumniRepository, uow repository.UnitOfWork, changes *ChangeLog) *AlumniService {
+	return &AlumniService{repo: repo, uow: uow, changes: changes}
 }
 
 func (s *AlumniService) GetAllService(c *fiber.Ctx) error {
@@ -35,9 +41,24 @@ func (s *AlumniService) GetAllService(c *fiber.Ctx) error {
 		order = "asc"
 	}
 
-	alumni, err := s.repo.GetAll(search, sortBy, order, limit, offset)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	var alumni []model.Alumni
+	if middleware.HasPermission(c, "alumni:manage") {
+		var err error
+		alumni, err = s.repo.GetAll(c.UserContext(), search, sortBy, order, limit, offset)
+		if err != nil {
+			return dbError(c, err)
+		}
+	} else {
+		// Tanpa alumni:manage, daftar hanya berisi record milik sendiri
+		if alumniID, ok := middleware.CurrentAlumniID(c); ok {
+			own, err := s.repo.GetByID(c.UserContext(), alumniID)
+			if err != nil && err != sql.ErrNoRows {
+				return dbError(c, err)
+			}
+			if own != nil {
+				alumni = append(alumni, *own)
+			}
+		}
 	}
 
 	response := model.AlumniResponse{
@@ -58,12 +79,15 @@ func (s *AlumniService) GetByIDService(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
 	}
-	data, err := s.repo.GetByID(id)
+	if !canAccessAlumni(c, id) {
+		return forbiddenAlumni(c)
+	}
+	data, err := s.repo.GetByID(c.UserContext(), id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{"error": "Alumni tidak ditemukan"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "data": data})
 }
@@ -73,30 +97,205 @@ func (s *AlumniService) CreateService(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
 	}
-	alumni, err := s.repo.Create(req)
+	if len(req.Pekerjaan) > 0 && !middleware.HasPermission(c, "pekerjaan:write") {
+		return c.Status(403).JSON(fiber.Map{"error": "Anda tidak memiliki izin menambah pekerjaan"})
+	}
+	username := c.Locals("username").(string)
+	for i := range req.Pekerjaan {
+		job := &req.Pekerjaan[i]
+		if msg := normalizeTanggalPekerjaan(&job.TanggalMulaiKerja, job.TanggalSelesaiKerja); msg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("pekerjaan[%d]: %s", i, msg)})
+		}
+		job.CreatedBy = utils.StringPtr(username)
+	}
+
+	// Alumni dan riwayat pekerjaannya tersimpan semua atau tidak sama sekali
+	var alumni *model.Alumni
+	var jobs []model.Pekerjaan
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		changes := s.changes.In(tx)
+		var err error
+		if alumni, err = tx.Alumni.Create(c.UserContext(), req); err != nil {
+			return err
+		}
+		if err := changes.Record(c, "alumni.create", "alumni", alumni.ID, nil, alumni); err != nil {
+			return err
+		}
+		for _, job := range req.Pekerjaan {
+			job.AlumniID = alumni.ID
+			p, err := tx.Pekerjaan.Create(c.UserContext(), job)
+			if err != nil {
+				return err
+			}
+			if err := changes.Record(c, "pekerjaan.create", "pekerjaan", p.ID, nil, p); err != nil {
+				return err
+			}
+			jobs = append(jobs, *p)
+		}
+		return nil
+	})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
+	}
+	if len(jobs) > 0 {
+		return c.JSON(fiber.Map{"success": true, "data": alumni, "pekerjaan": jobs})
 	}
 	return c.JSON(fiber.Map{"success": true, "data": alumni})
 }
 
 func (s *AlumniService) UpdateService(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
+	if !canAccessAlumni(c, id) {
+		return forbiddenAlumni(c)
+	}
 	var req model.UpdateAlumniRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
 	}
-	alumni, err := s.repo.Update(id, req)
+	var alumni *model.Alumni
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		before, err := tx.Alumni.GetByIDForUpdate(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		if alumni, err = tx.Alumni.Update(c.UserContext(), id, req); err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "alumni.update", "alumni", id, before, alumni)
+	})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return alumniError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "data": alumni})
 }
 
 func (s *AlumniService) DeleteService(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
-	if err := s.repo.Delete(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	if !canAccessAlumni(c, id) {
+		return forbiddenAlumni(c)
+	}
+	// Pekerjaan milik alumni dihapus satu per satu (bukan lewat ON DELETE
+	// CASCADE) supaya tiap pekerjaan tetap punya jejak audit dan revisi terakhir.
+	// Baris alumni dikunci dulu sehingga pekerjaan baru tidak bisa menyusup.
+	var removed int
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		changes := s.changes.In(tx)
+		before, err := tx.Alumni.GetByIDForUpdate(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		jobs, err := tx.Pekerjaan.GetByAlumniIDWithTrash(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if err := tx.Pekerjaan.HardDelete(c.UserContext(), job.ID); err != nil {
+				return err
+			}
+			if err := changes.Record(c, "pekerjaan.hard_delete", "pekerjaan", job.ID, job, nil); err != nil {
+				return err
+			}
+		}
+		removed = len(jobs)
+		if err := tx.Alumni.Delete(c.UserContext(), id); err != nil {
+			return err
+		}
+		return changes.Record(c, "alumni.delete", "alumni", id, before, nil)
+	})
+	if err != nil {
+		return alumniError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Alumni dihapus", "pekerjaan_dihapus": removed})
+}
+
+// GET /alumni/:id/history
+func (s *AlumniService) HistoryService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if !canAccessAlumni(c, id) {
+		return forbiddenAlumni(c)
+	}
+	revisions, err := s.changes.History(c.UserContext(), "alumni", id)
+	if err != nil {
+		return dbError(c, err)
+	}
+	// Alumni lama yang belum pernah diubah memang belum punya revisi
+	if len(revisions) == 0 {
+		if _, err := s.repo.GetByID(c.UserContext(), id); err != nil {
+			return alumniError(c, err)
+		}
+	}
+	return c.JSON(fiber.Map{"success": true, "data": revisions})
+}
+
+// POST /alumni/:id/revert/:revision
+func (s *AlumniService) RevertService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	revision, err := strconv.Atoi(c.Params("revision"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Nomor revisi tidak valid"})
+	}
+
+	rev, err := s.changes.Revision(c.UserContext(), "alumni", id, revision)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": "Revisi tidak ditemukan"})
+		}
+		return dbError(c, err)
+	}
+	var snapshot model.Alumni
+	if err := json.Unmarshal(rev.Data, &snapshot); err != nil {
+		return dbError(c, err)
+	}
+
+	// Alumni yang sudah dihapus permanen tidak bisa dikembalikan lewat revert
+	var alumni *model.Alumni
+	err = s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		before, err := tx.Alumni.GetByIDForUpdate(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		alumni, err = tx.Alumni.Update(c.UserContext(), id, model.UpdateAlumniRequest{
+			Nama:       snapshot.Nama,
+			Jurusan:    snapshot.Jurusan,
+			Angkatan:   snapshot.Angkatan,
+			TahunLulus: snapshot.TahunLulus,
+			Email:      snapshot.Email,
+			NoTelepon:  snapshot.NoTelepon,
+			Alamat:     snapshot.Alamat,
+		})
+		if err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "alumni.revert", "alumni", id, before, alumni)
+	})
+	if err != nil {
+		return alumniError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": alumni, "reverted_to": revision})
+}
+
+// canAccessAlumni -> user tanpa alumni:manage hanya boleh menyentuh record alumni miliknya
+func canAccessAlumni(c *fiber.Ctx, alumniID int) bool {
+	if middleware.HasPermission(c, "alumni:manage") {
+		return true
+	}
+	own, ok := middleware.CurrentAlumniID(c)
+	return ok && own == alumniID
+}
+
+func forbiddenAlumni(c *fiber.Ctx) error {
+	return c.Status(403).JSON(fiber.Map{"error": "Anda hanya dapat mengakses data alumni milik sendiri"})
+}
+
+func alumniError(c *fiber.Ctx, err error) error {
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{"error": "Alumni tidak ditemukan"})
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "Alumni dihapus"})
+	return dbError(c, err)
 }
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/middleware"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultAPIKeyTTL = 90 * 24 * time.Hour
+	maxAPIKeyTTL     = 365 * 24 * time.Hour
+)
+
+type APIKeyService struct {
+	keys        repository.APIKeyRepository
+	permissions repository.PermissionRepository
+}
+
+func NewAPIKeyService(keys repository.APIKeyRepository, permissions repository.PermissionRepository) *APIKeyService {
+	return &APIKeyService{keys: keys, permissions: permissions}
+}
+
+// GET /admin/api-keys
+func (s *APIKeyService) GetAllService(c *fiber.Ctx) error {
+	keys, err := s.keys.List(c.UserContext())
+	if err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": keys})
+}
+
+// POST /admin/api-keys
+func (s *APIKeyService) CreateService(c *fiber.Ctx) error {
+	var req model.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "name dan minimal satu scope wajib diisi"})
+	}
+
+	expiresAt := time.Now().Add(defaultAPIKeyTTL)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(time.Now()) || time.Until(expiresAt) > maxAPIKeyTTL {
+		return c.Status(400).JSON(fiber.Map{"error": "expires_at harus di masa depan dan paling lama 1 tahun"})
+	}
+
+	// Scope adalah nama permission, jadi harus terdaftar di tabel permissions
+	known, err := s.permissions.ListAll(c.UserContext())
+	if err != nil {
+		return dbError(c, err)
+	}
+	valid := make(map[string]bool, len(known))
+	for _, p := range known {
+		valid[p] = true
+	}
+	for _, scope := range req.Scopes {
+		if !valid[scope] {
+			return c.Status(400).JSON(fiber.Map{"error": "Scope tidak dikenal: " + scope})
+		}
+		// Key tidak boleh lebih berkuasa dari pembuatnya
+		if !middleware.HasPermission(c, scope) {
+			return c.Status(403).JSON(fiber.Map{"error": "Anda tidak dapat memberikan scope yang tidak Anda miliki: " + scope})
+		}
+	}
+
+	rawKey, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return dbError(c, err)
+	}
+	createdBy := c.Locals("user_id").(int)
+	key := model.APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(rawKey),
+		Scopes:    req.Scopes,
+		CreatedBy: &createdBy,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.keys.Create(c.UserContext(), &key); err != nil {
+		return dbError(c, err)
+	}
+
+	log.Printf("Admin %v membuat API key %q (%s) dengan scope %v", c.Locals("username"), key.Name, key.Prefix, key.Scopes)
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Simpan key ini sekarang, key tidak akan ditampilkan lagi",
+		"data":    model.CreateAPIKeyResponse{APIKey: key, Key: rawKey},
+	})
+}
+
+// DELETE /admin/api-keys/:id
+func (s *APIKeyService) RevokeService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if err := s.keys.Revoke(c.UserContext(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(404).JSON(fiber.Map{"error": "API key tidak ditemukan"})
+		}
+		return dbError(c, err)
+	}
+	log.Printf("Admin %v mencabut API key %d", c.Locals("username"), id)
+	return c.JSON(fiber.Map{"success": true, "message": "API key dicabut"})
+}
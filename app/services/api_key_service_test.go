@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeAPIKeys struct {
+	repository.APIKeyRepository
+	created []model.APIKey
+}
+
+func (f *fakeAPIKeys) Create(ctx context.Context, key *model.APIKey) error {
+	key.ID = len(f.created) + 1
+	f.created = append(f.created, *key)
+	return nil
+}
+
+type fakePermissions struct {
+	repository.PermissionRepository
+	all []string
+}
+
+func (f *fakePermissions) ListAll(ctx context.Context) ([]string, error) {
+	return f.all, nil
+}
+
+func newAPIKeyApp(callerPerms ...string) (*fiber.App, *fakeAPIKeys) {
+	keys := &fakeAPIKeys{}
+	svc := NewAPIKeyService(keys, &fakePermissions{all: []string{"alumni:read", "alumni:manage", "users:manage"}})
+
+	perms := make(map[string]bool, len(callerPerms))
+	for _, p := range callerPerms {
+		perms[p] = true
+	}
+	app := fiber.New()
+	app.Post("/api/admin/api-keys", func(c *fiber.Ctx) error {
+		c.Locals("user_id", 1)
+		c.Locals("username", "operator")
+		c.Locals("permissions", perms)
+		return c.Next()
+	}, svc.CreateService)
+	return app, keys
+}
+
+func TestCreateAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes string
+		status int
+	}{
+		{"scope yang dimiliki", `["alumni:read"]`, 201},
+		{"scope tidak dimiliki pembuat", `["alumni:read","users:manage"]`, 403},
+		{"scope tidak dikenal", `["alumni:delete"]`, 400},
+		{"tanpa scope", `[]`, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, keys := newAPIKeyApp("api_keys:manage", "alumni:read", "alumni:manage")
+			status, body := doJSON(t, app, "POST", "/api/admin/api-keys", `{"name":"integrasi","scopes":`+tt.scopes+`}`)
+			if status != tt.status {
+				t.Fatalf("status %d, seharusnya %d (%s)", status, tt.status, body)
+			}
+			if created := len(keys.created) == 1; created != (tt.status == 201) {
+				t.Errorf("key tersimpan = %v, status %d", created, status)
+			}
+		})
+	}
+}
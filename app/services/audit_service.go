@@ -0,0 +1,88 @@
+package services
+
+import (
+	"strconv"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditService struct {
+	audit repository.AuditRepository
+}
+
+func NewAuditService(audit repository.AuditRepository) *AuditService {
+	return &AuditService{audit: audit}
+}
+
+// GET /admin/audit?page=&limit=&action=&actor_id=&target_type=&target_id=&request_id=&from=&to=
+func (s *AuditService) GetAllService(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	filter := model.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		RequestID:  c.Query("request_id"),
+	}
+	if v := c.Query("actor_id"); v != "" {
+		actorID, err := strconv.Atoi(v)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "actor_id tidak valid"})
+		}
+		filter.ActorID = &actorID
+	}
+	var err error
+	if filter.From, err = parseAuditTime(c.Query("from")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Format from salah, gunakan RFC3339 atau YYYY-MM-DD"})
+	}
+	if filter.To, err = parseAuditTime(c.Query("to")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Format to salah, gunakan RFC3339 atau YYYY-MM-DD"})
+	}
+
+	entries, err := s.audit.List(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		return dbError(c, err)
+	}
+	total, err := s.audit.Count(c.UserContext(), filter)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	return c.JSON(model.AuditResponse{
+		Data: entries,
+		Meta: model.MetaInfo{
+			Page:   page,
+			Limit:  limit,
+			Total:  total,
+			Pages:  (total + limit - 1) / limit,
+			SortBy: "occurred_at",
+			Order:  "desc",
+		},
+	})
+}
+
+// parseAuditTime -> tanggal saja berarti awal hari itu (UTC)
+func parseAuditTime(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
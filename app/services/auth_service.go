@@ -1,15 +1,44 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 	"tugas5/app/model"
 	"tugas5/app/repository"
+	"tugas5/config"
+	"tugas5/middleware"
 	"tugas5/utils"
-	"database/sql"
-	"errors"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
-func LoginService(c *fiber.Ctx, db *sql.DB) error {
+type AuthService struct {
+	authenticator repository.Authenticator
+	users         repository.UserRepository
+	sessions      repository.SessionRepository
+	throttle      *loginThrottle
+	cfg           *config.Config
+}
+
+func NewAuthService(authenticator repository.Authenticator, users repository.UserRepository, sessions repository.SessionRepository,
+	throttle repository.LoginThrottleRepository, cfg *config.Config) *AuthService {
+	return &AuthService{
+		authenticator: authenticator,
+		users:         users,
+		sessions:      sessions,
+		throttle:      &loginThrottle{repo: throttle, cfg: cfg.Auth},
+		cfg:           cfg,
+	}
+}
+
+// POST /login
+func (s *AuthService) LoginService(c *fiber.Ctx) error {
 	var loginData model.LoginRequest
 	if err := c.BodyParser(&loginData); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -25,22 +54,154 @@ func LoginService(c *fiber.Ctx, db *sql.DB) error {
 		})
 	}
 
-	user, err := repository.Login(db, loginData.Username, loginData.Password)
+	lockedFor, err := s.throttle.lockedFor(c.UserContext(), loginData.Username, c.IP())
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
+	}
+	if lockedFor > 0 {
+		retryAfter := int(math.Ceil(lockedFor.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"message":     "Terlalu banyak percobaan login gagal, coba lagi nanti",
+			"retry_after": retryAfter,
+			"success":     false,
+		})
+	}
+
+	user, err := s.authenticator.Authenticate(c.UserContext(), loginData.Username, loginData.Password)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repository.ErrInvalidCredentials) {
+			if err := s.throttle.recordFailure(c.UserContext(), loginData.Username, c.IP()); err != nil {
+				log.Println("Gagal mencatat login gagal:", err)
+			}
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"message": "Username atau password salah",
 				"success": false,
 			})
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		if errors.Is(err, repository.ErrAccountDisabled) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Akun Anda dinonaktifkan, hubungi admin",
+				"success": false,
+			})
+		}
+		if errors.Is(err, repository.ErrEmailNotVerified) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Email belum diverifikasi, cek email Anda",
+				"success": false,
+			})
+		}
+		if errors.Is(err, repository.ErrNoRoleMapping) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Akun direktori Anda belum diberi akses ke sistem ini, hubungi admin",
+				"success": false,
+			})
+		}
+		if errors.Is(err, repository.ErrDirectoryNotLinked) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Akun direktori Anda bentrok dengan akun lokal yang belum dihubungkan, hubungi admin",
+				"success": false,
+			})
+		}
+		if errors.Is(err, repository.ErrDirectoryNoEmail) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Akun direktori Anda tidak punya alamat email, hubungi admin",
+				"success": false,
+			})
+		}
+		log.Println("Login gagal:", err)
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
+	}
+
+	// Password benar tapi akun memakai (atau wajib memakai) 2FA: sesi baru
+	// dibuat setelah kode TOTP dicek di /login/2fa
+	if user.TOTPEnabled || s.cfg.Auth.MFARequired(user.Role) {
+		return s.requireSecondFactor(c, user)
+	}
+
+	if err := s.throttle.recordSuccess(c.UserContext(), loginData.Username); err != nil {
+		log.Println("Gagal mereset hitungan login gagal:", err)
+	}
+
+	response, err := s.startSession(c, user)
+	if err != nil {
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal membuat token",
+			"success": false,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// POST /refresh
+func (s *AuthService) RefreshService(c *fiber.Ctx) error {
+	var req model.RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Refresh token diperlukan",
+			"success": false,
+		})
+	}
+
+	oldHash := utils.HashToken(req.RefreshToken)
+	session, err := s.sessions.GetByRefreshHash(c.UserContext(), oldHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.handleUnknownRefreshToken(c, oldHash)
+		}
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Sesi sudah berakhir, silakan login ulang",
+			"success": false,
+		})
+	}
+
+	user, err := s.users.GetByID(c.UserContext(), session.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "User tidak ditemukan",
+				"success": false,
+			})
+		}
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
 			"message": "Gagal terhubung ke database",
 			"success": false,
 		})
 	}
+	if !user.IsActive {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Akun Anda dinonaktifkan, hubungi admin",
+			"success": false,
+		})
+	}
+	// Sesi yang dibuat sebelum 2FA diwajibkan tidak boleh diperpanjang terus
+	if s.cfg.Auth.MFARequired(user.Role) && !user.TOTPEnabled {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Role Anda wajib memakai 2FA, silakan login ulang",
+			"success": false,
+		})
+	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user)
+	refreshToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Gagal membuat token",
+			"success": false,
+		})
+	}
+	token, claims, err := utils.GenerateToken(*user, session.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Gagal membuat token",
@@ -48,11 +209,162 @@ func LoginService(c *fiber.Ctx, db *sql.DB) error {
 		})
 	}
 
-	// Gunakan LoginResponse biar konsisten
-	response := model.LoginResponse{
-		Token: token,
-		User:  user,
+	err = s.sessions.Rotate(c.UserContext(), session.ID, oldHash, utils.HashToken(refreshToken), claims.ID, claims.ExpiresAt.Time,
+		middleware.ClientInfo(c))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Token ini baru saja dirotasi oleh request lain
+			return s.handleUnknownRefreshToken(c, oldHash)
+		}
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(response)
-}
\ No newline at end of file
+	return c.JSON(model.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+		User:         *user,
+	})
+}
+
+// handleUnknownRefreshToken -> refresh token lama yang dipakai ulang berarti
+// token kemungkinan dicuri, jadi seluruh sesi tersebut langsung dicabut
+func (s *AuthService) handleUnknownRefreshToken(c *fiber.Ctx, hash string) error {
+	session, err := s.sessions.GetByPreviousHash(c.UserContext(), hash)
+	if err == nil && session.RevokedAt == nil {
+		log.Printf("Refresh token dipakai ulang untuk sesi %s (user %d), sesi dicabut", session.ID, session.UserID)
+		if err := s.sessions.Revoke(c.UserContext(), session.ID); err != nil {
+			return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+				"message": "Gagal terhubung ke database",
+				"success": false,
+			})
+		}
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"message": "Refresh token tidak valid",
+		"success": false,
+	})
+}
+
+// POST /logout
+func (s *AuthService) LogoutService(c *fiber.Ctx) error {
+	sessionID, _ := c.Locals("session_id").(string)
+	jti, _ := c.Locals("jti").(string)
+	expiresAt, _ := c.Locals("token_expires_at").(time.Time)
+
+	// Logout dengan token impersonasi hanya mengakhiri token itu; sesi milik
+	// user yang diimpersonasi tidak boleh ikut tersentuh
+	if middleware.IsImpersonating(c) {
+		sessionID = ""
+	}
+	if sessionID != "" {
+		if err := s.sessions.Revoke(c.UserContext(), sessionID); err != nil {
+			return dbError(c, err)
+		}
+	}
+	if err := s.sessions.RevokeToken(c.UserContext(), jti, expiresAt); err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Berhasil logout"})
+}
+
+// DELETE /admin/users/:id/sessions
+func (s *AuthService) RevokeUserSessionsService(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if err := s.sessions.RevokeAllForUser(c.UserContext(), userID); err != nil {
+		return dbError(c, err)
+	}
+	// Token impersonasi tidak punya baris sesi, jadi dicabut lewat token_version
+	if err := s.users.BumpTokenVersion(c.UserContext(), userID); err != nil {
+		return userError(c, err)
+	}
+	log.Printf("Admin %v mencabut semua sesi user %d", c.Locals("username"), userID)
+	return c.JSON(fiber.Map{"success": true, "message": "Semua sesi user dicabut"})
+}
+
+// GET /admin/lockouts
+func (s *AuthService) GetLockoutsService(c *fiber.Ctx) error {
+	locks, err := s.throttle.repo.ListLocked(c.UserContext())
+	if err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": locks})
+}
+
+// DELETE /admin/lockouts?key=ip:10.0.0.1
+func (s *AuthService) ClearLockoutService(c *fiber.Ctx) error {
+	key := c.Query("key")
+	if !strings.HasPrefix(key, "user:") && !strings.HasPrefix(key, "ip:") {
+		return c.Status(400).JSON(fiber.Map{"error": "key harus berbentuk user:<username> atau ip:<alamat>"})
+	}
+	return s.unlock(c, key)
+}
+
+// POST /admin/users/:id/unlock
+func (s *AuthService) UnlockUserService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	user, err := s.users.GetByID(c.UserContext(), id)
+	if err != nil {
+		return userError(c, err)
+	}
+	return s.unlock(c, userThrottleKey(user.Username))
+}
+
+func (s *AuthService) unlock(c *fiber.Ctx, key string) error {
+	wasLocked, err := s.throttle.repo.Reset(c.UserContext(), key)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if wasLocked {
+		log.Printf("Login dibuka: %s oleh admin %v", key, c.Locals("username"))
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Kunci login dibuka", "was_locked": wasLocked})
+}
+
+// startSession -> buat sesi baru lalu terbitkan pasangan access + refresh token
+func (s *AuthService) startSession(c *fiber.Ctx, user model.User) (*model.LoginResponse, error) {
+	refreshToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	sessionID := uuid.NewString()
+	token, claims, err := utils.GenerateToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &model.Session{
+		ID:              sessionID,
+		UserID:          user.ID,
+		AccessJTI:       claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       time.Now().Add(s.cfg.JWT.RefreshTTL),
+	}
+	client := middleware.ClientInfo(c)
+	session.UserAgent, session.IP, session.Device = client.UserAgent, client.IP, client.Device
+	if err := s.sessions.Create(c.UserContext(), session, utils.HashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
+
+// GET /.well-known/jwks.json
+func JWKSService(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(utils.JWKS())
+}
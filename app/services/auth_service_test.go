@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"tugas5/app/repository"
+	"tugas5/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// logoutSessions -> mencatat sesi dan jti yang dicabut
+type logoutSessions struct {
+	repository.SessionRepository
+	revokedSessions []string
+	revokedTokens   []string
+	revokedAll      []int
+}
+
+func (f *logoutSessions) Revoke(ctx context.Context, id string) error {
+	f.revokedSessions = append(f.revokedSessions, id)
+	return nil
+}
+
+func (f *logoutSessions) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	f.revokedTokens = append(f.revokedTokens, jti)
+	return nil
+}
+
+func (f *logoutSessions) RevokeAllForUser(ctx context.Context, userID int) error {
+	f.revokedAll = append(f.revokedAll, userID)
+	return nil
+}
+
+// tokenVersionUsers -> hanya mencatat user yang token_version-nya dinaikkan
+type tokenVersionUsers struct {
+	repository.UserRepository
+	bumped []int
+}
+
+func (f *tokenVersionUsers) BumpTokenVersion(ctx context.Context, id int) error {
+	f.bumped = append(f.bumped, id)
+	return nil
+}
+
+// newLogoutApp -> locals diisi seperti AuthRequired untuk token sesi biasa
+// atau token impersonasi (tanpa sesi, dengan impersonator_id)
+func newLogoutApp(sessions *logoutSessions, users *tokenVersionUsers, impersonating bool) *fiber.App {
+	svc := NewAuthService(nil, users, sessions, nil, &config.Config{})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", 7)
+		c.Locals("username", "budi")
+		c.Locals("jti", "jti-budi")
+		c.Locals("token_expires_at", time.Now().Add(time.Minute))
+		if impersonating {
+			c.Locals("jti", "jti-impersonasi")
+			c.Locals("impersonator_id", 1)
+		} else {
+			c.Locals("session_id", "sesi-budi")
+		}
+		return c.Next()
+	})
+	app.Post("/api/logout", svc.LogoutService)
+	app.Delete("/api/admin/users/:id/sessions", svc.RevokeUserSessionsService)
+	return app
+}
+
+func TestLogoutRevokesSessionAndToken(t *testing.T) {
+	sessions := &logoutSessions{}
+	app := newLogoutApp(sessions, &tokenVersionUsers{}, false)
+
+	if status, body := doJSON(t, app, "POST", "/api/logout", ""); status != 200 {
+		t.Fatalf("logout: status %d, body %s", status, body)
+	}
+	if len(sessions.revokedSessions) != 1 || sessions.revokedSessions[0] != "sesi-budi" {
+		t.Errorf("sesi yang dicabut %v, seharusnya [sesi-budi]", sessions.revokedSessions)
+	}
+	if len(sessions.revokedTokens) != 1 || sessions.revokedTokens[0] != "jti-budi" {
+		t.Errorf("jti yang dicabut %v, seharusnya [jti-budi]", sessions.revokedTokens)
+	}
+}
+
+func TestLogoutWithImpersonationTokenOnlyRevokesItsJTI(t *testing.T) {
+	sessions := &logoutSessions{}
+	app := newLogoutApp(sessions, &tokenVersionUsers{}, true)
+
+	if status, body := doJSON(t, app, "POST", "/api/logout", ""); status != 200 {
+		t.Fatalf("logout: status %d, body %s", status, body)
+	}
+	if len(sessions.revokedSessions) != 0 || len(sessions.revokedAll) != 0 {
+		t.Errorf("sesi milik user yang diimpersonasi tidak boleh dicabut: %v %v", sessions.revokedSessions, sessions.revokedAll)
+	}
+	if len(sessions.revokedTokens) != 1 || sessions.revokedTokens[0] != "jti-impersonasi" {
+		t.Errorf("jti yang dicabut %v, seharusnya [jti-impersonasi]", sessions.revokedTokens)
+	}
+}
+
+func TestRevokeUserSessionsBumpsTokenVersion(t *testing.T) {
+	sessions, users := &logoutSessions{}, &tokenVersionUsers{}
+	app := newLogoutApp(sessions, users, false)
+
+	if status, body := doJSON(t, app, "DELETE", "/api/admin/users/9/sessions", ""); status != 200 {
+		t.Fatalf("cabut sesi: status %d, body %s", status, body)
+	}
+	if len(sessions.revokedAll) != 1 || sessions.revokedAll[0] != 9 {
+		t.Errorf("sesi yang dicabut %v, seharusnya milik user 9", sessions.revokedAll)
+	}
+	// Token impersonasi tidak punya sesi; hanya token_version yang membatalkannya
+	if len(users.bumped) != 1 || users.bumped[0] != 9 {
+		t.Errorf("token_version yang dinaikkan %v, seharusnya milik user 9", users.bumped)
+	}
+}
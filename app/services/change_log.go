@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// revisionIgnoredFields -> field yang selalu berubah dan tidak berarti apa-apa di diff
+var revisionIgnoredFields = map[string]bool{"updated_at": true}
+
+// ChangeLog -> jejak setiap perubahan alumni/pekerjaan: entri audit_log dan
+// snapshot revisi untuk riwayat dan revert
+type ChangeLog struct {
+	audit     repository.AuditRepository
+	revisions repository.RevisionRepository
+}
+
+func NewChangeLog(audit repository.AuditRepository, revisions repository.RevisionRepository) *ChangeLog {
+	return &ChangeLog{audit: audit, revisions: revisions}
+}
+
+// In -> ChangeLog yang menulis lewat repository unit of work, sehingga jejak
+// perubahan ikut di-commit atau di-rollback bersama datanya
+func (l *ChangeLog) In(tx repository.Repositories) *ChangeLog {
+	return &ChangeLog{audit: tx.Audit, revisions: tx.Revisions}
+}
+
+// Record -> catat perubahan data setelah berhasil disimpan. before/after nil
+// berarti data belum ada (create) atau sudah tidak ada (hard delete).
+// Kegagalan selalu di-log; di dalam unit of work error-nya dikembalikan supaya
+// perubahan ikut dibatalkan, di luar transaksi perubahannya sudah tersimpan.
+func (l *ChangeLog) Record(c *fiber.Ctx, action, targetType string, targetID int, before, after any) error {
+	entry := middleware.NewAuditEntry(c, action)
+	entry.TargetType = targetType
+	entry.TargetID = strconv.Itoa(targetID)
+	status := fiber.StatusOK
+	entry.Status = &status
+
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			log.Printf("Gagal menyusun audit %s %s/%d: %v", action, targetType, targetID, err)
+			return err
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			log.Printf("Gagal menyusun audit %s %s/%d: %v", action, targetType, targetID, err)
+			return err
+		}
+	}
+	if err := l.audit.Record(c.UserContext(), entry); err != nil {
+		log.Printf("Gagal mencatat audit %s %s/%d: %v", action, targetType, targetID, err)
+		return err
+	}
+
+	// Revisi menyimpan kondisi data setelah perubahan; untuk hapus permanen
+	// yang disimpan adalah data terakhir sebelum dihapus
+	snapshot := entry.After
+	if snapshot == nil {
+		snapshot = entry.Before
+	}
+	if snapshot == nil {
+		return nil
+	}
+	rev := &model.Revision{
+		EntityType:      targetType,
+		EntityID:        targetID,
+		Action:          strings.TrimPrefix(action, targetType+"."),
+		Data:            snapshot,
+		ChangedByUserID: entry.ActorUserID,
+		ChangedBy:       entry.ActorUsername,
+		RequestID:       entry.RequestID,
+	}
+	// Data lama yang dibuat sebelum ada riwayat revisi mendapat revisi awal dari kondisi sebelum diubah
+	var baseline *model.Revision
+	if entry.Before != nil {
+		baseline = &model.Revision{
+			EntityType: targetType,
+			EntityID:   targetID,
+			Action:     "baseline",
+			Data:       entry.Before,
+			RequestID:  entry.RequestID,
+		}
+	}
+	if err := l.revisions.Record(c.UserContext(), rev, baseline); err != nil {
+		log.Printf("Gagal menyimpan revisi %s %s/%d: %v", action, targetType, targetID, err)
+		return err
+	}
+	return nil
+}
+
+// History -> semua revisi beserta field yang berubah dari revisi sebelumnya
+func (l *ChangeLog) History(ctx context.Context, targetType string, targetID int) ([]model.Revision, error) {
+	revisions, err := l.revisions.List(ctx, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	var previous json.RawMessage
+	for i := range revisions {
+		changes, err := diffSnapshots(previous, revisions[i].Data)
+		if err != nil {
+			return nil, err
+		}
+		revisions[i].Changes = changes
+		previous = revisions[i].Data
+	}
+	return revisions, nil
+}
+
+func (l *ChangeLog) Revision(ctx context.Context, targetType string, targetID, revision int) (*model.Revision, error) {
+	return l.revisions.Get(ctx, targetType, targetID, revision)
+}
+
+// diffSnapshots -> field yang berbeda, urut nama field; field yang tidak ada
+// dianggap null (field omitempty hilang dari JSON kalau nil)
+func diffSnapshots(before, after json.RawMessage) ([]model.FieldChange, error) {
+	oldFields, err := snapshotFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := snapshotFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+
+	changes := []model.FieldChange{}
+	for name := range names {
+		if revisionIgnoredFields[name] {
+			continue
+		}
+		from, to := oldFields[name], newFields[name]
+		if bytes.Equal(from, to) {
+			continue
+		}
+		changes = append(changes, model.FieldChange{Field: name, From: from, To: to})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func snapshotFields(data json.RawMessage) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if len(data) == 0 {
+		return fields, nil
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	// JSONB menormalkan spasi, tetapi null eksplisit disamakan dengan field yang tidak ada
+	for name, value := range fields {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return nil, err
+		}
+		if compact.String() == "null" {
+			delete(fields, name)
+			continue
+		}
+		fields[name] = compact.Bytes()
+	}
+	return fields, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+)
+
+// changeList -> "field: from -> to" supaya hasil diff mudah dibandingkan;
+// field yang tidak ada ditulis null
+func changeList(changes []model.FieldChange) []string {
+	list := []string{}
+	for _, ch := range changes {
+		from, to := string(ch.From), string(ch.To)
+		if from == "" {
+			from = "null"
+		}
+		if to == "" {
+			to = "null"
+		}
+		list = append(list, ch.Field+": "+from+" -> "+to)
+	}
+	return list
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          []string
+	}{
+		{
+			name:   "field berubah",
+			before: `{"id":1,"posisi_jabatan":"Staff"}`,
+			after:  `{"id":1,"posisi_jabatan":"Manager"}`,
+			want:   []string{`posisi_jabatan: "Staff" -> "Manager"`},
+		},
+		{
+			name:   "field tidak berubah, beda spasi saja",
+			before: `{"id":1,"lokasi_kerja":{"kota": "Bandung"}}`,
+			after:  `{"id": 1, "lokasi_kerja":{"kota":"Bandung"}}`,
+			want:   []string{},
+		},
+		{
+			name:   "updated_at diabaikan",
+			before: `{"id":1,"updated_at":"2024-01-01T00:00:00Z"}`,
+			after:  `{"id":1,"updated_at":"2024-02-01T00:00:00Z"}`,
+			want:   []string{},
+		},
+		{
+			name:   "nil menjadi nilai",
+			before: `{"id":1,"gaji_range":null}`,
+			after:  `{"id":1,"gaji_range":"5-10jt","deskripsi_pekerjaan":"Backend"}`,
+			want:   []string{`deskripsi_pekerjaan: null -> "Backend"`, `gaji_range: null -> "5-10jt"`},
+		},
+		{
+			name:   "nilai menjadi nil",
+			before: `{"id":1,"tanggal_selesai_kerja":"2024-05-01","gaji_range":"5-10jt"}`,
+			after:  `{"id":1,"tanggal_selesai_kerja":null}`,
+			want:   []string{`gaji_range: "5-10jt" -> null`, `tanggal_selesai_kerja: "2024-05-01" -> null`},
+		},
+		{
+			name:   "null eksplisit sama dengan field yang hilang",
+			before: `{"id":1,"created_by":null}`,
+			after:  `{"id":1}`,
+			want:   []string{},
+		},
+		{
+			name:   "revisi awal dibandingkan dengan kosong",
+			before: ``,
+			after:  `{"id":1,"nama_perusahaan":"PT Maju","gaji_range":null}`,
+			want:   []string{`id: null -> 1`, `nama_perusahaan: null -> "PT Maju"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := diffSnapshots(json.RawMessage(tt.before), json.RawMessage(tt.after))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := changeList(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %q, seharusnya %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshotsRejectsInvalidJSON(t *testing.T) {
+	if _, err := diffSnapshots(json.RawMessage(`{"id":1}`), json.RawMessage(`{"id":`)); err == nil {
+		t.Error("snapshot rusak seharusnya error")
+	}
+}
+
+type fakeRevisions struct {
+	repository.RevisionRepository
+	list []model.Revision
+}
+
+func (f *fakeRevisions) List(ctx context.Context, entityType string, entityID int) ([]model.Revision, error) {
+	return f.list, nil
+}
+
+func TestHistoryDiffsAgainstPreviousRevision(t *testing.T) {
+	revisions := &fakeRevisions{list: []model.Revision{
+		{Revision: 1, Action: "baseline", Data: json.RawMessage(`{"id":3,"posisi_jabatan":"Staff","gaji_range":null}`)},
+		{Revision: 2, Action: "update", Data: json.RawMessage(`{"id":3,"posisi_jabatan":"Manager","gaji_range":"10-15jt"}`)},
+		{Revision: 3, Action: "revert", Data: json.RawMessage(`{"id":3,"posisi_jabatan":"Staff","gaji_range":null}`)},
+	}}
+	history, err := NewChangeLog(nil, revisions).History(context.Background(), "pekerjaan", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{`id: null -> 3`, `posisi_jabatan: null -> "Staff"`},
+		{`gaji_range: null -> "10-15jt"`, `posisi_jabatan: "Staff" -> "Manager"`},
+		{`gaji_range: "10-15jt" -> null`, `posisi_jabatan: "Manager" -> "Staff"`},
+	}
+	for i, rev := range history {
+		if got := changeList(rev.Changes); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("revisi %d: diff = %q, seharusnya %q", rev.Revision, got, want[i])
+		}
+	}
+}
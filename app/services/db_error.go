@@ -0,0 +1,13 @@
+package services
+
+import (
+	"tugas5/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// dbError -> error yang tidak ditangani khusus; query yang melewati batas waktu
+// dijawab 504 dan request yang dibatalkan 503, bukan 500
+func dbError(c *fiber.Ctx, err error) error {
+	return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{"error": middleware.DBErrorMessage(err)})
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"tugas5/config"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// testConfig -> konfigurasi minimal untuk service akun; email ditulis mailer
+// file ke folder sementara
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	if err := utils.InitJWT(config.JWTConfig{
+		Issuer:      "alumni-api-test",
+		Algorithm:   "EdDSA",
+		KeyDir:      t.TempDir(),
+		KeyRotation: time.Hour,
+		KeyGrace:    time.Hour,
+		AccessTTL:   time.Minute,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return &config.Config{
+		App:  config.AppConfig{Env: "test", BaseURL: "http://alumni.test"},
+		Mail: config.MailConfig{Driver: "file", From: "Sistem Alumni <no-reply@localhost>", FileDir: t.TempDir()},
+	}
+}
+
+// outbox -> isi email yang ditulis mailer file, urut nama file (waktu kirim)
+func outbox(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	mails := make([]string, 0, len(files))
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mails = append(mails, string(b))
+	}
+	return mails
+}
+
+// doJSON -> kirim request ke app dan kembalikan status serta body-nya
+func doJSON(t *testing.T, app *fiber.App, method, target, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/middleware"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ImpersonationService struct {
+	users       repository.UserRepository
+	permissions repository.PermissionRepository
+	audit       repository.AuditRepository
+	ttl         time.Duration
+}
+
+func NewImpersonationService(users repository.UserRepository, permissions repository.PermissionRepository,
+	audit repository.AuditRepository, ttl time.Duration) *ImpersonationService {
+	return &ImpersonationService{users: users, permissions: permissions, audit: audit, ttl: ttl}
+}
+
+// POST /admin/impersonate/:user_id
+func (s *ImpersonationService) StartService(c *fiber.Ctx) error {
+	targetID, err := strconv.Atoi(c.Params("user_id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if isSelf(c, targetID) {
+		return c.Status(400).JSON(fiber.Map{"error": "Tidak bisa impersonasi akun sendiri"})
+	}
+
+	admin, err := s.users.GetByID(c.UserContext(), c.Locals("user_id").(int))
+	if err != nil {
+		return userError(c, err)
+	}
+	target, err := s.users.GetByID(c.UserContext(), targetID)
+	if err != nil {
+		return userError(c, err)
+	}
+	if !target.IsActive {
+		return c.Status(400).JSON(fiber.Map{"error": "User ini dinonaktifkan"})
+	}
+	// Admin lain tidak boleh di-impersonasi, supaya impersonasi tidak bisa
+	// dipakai untuk menyamarkan aksi admin sebagai aksi admin lain
+	targetPerms, err := s.permissions.GetByRole(c.UserContext(), target.Role)
+	if err != nil {
+		return dbError(c, err)
+	}
+	for _, p := range targetPerms {
+		if p == "users:impersonate" || p == "users:manage" {
+			return c.Status(403).JSON(fiber.Map{"error": "Akun admin tidak bisa di-impersonasi"})
+		}
+	}
+
+	token, claims, err := utils.GenerateImpersonationToken(*target, *admin, s.ttl)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+
+	details, _ := json.Marshal(fiber.Map{"jti": claims.ID, "expires_at": claims.ExpiresAt.Time})
+	entry := middleware.NewAuditEntry(c, "impersonation.start")
+	entry.TargetType = "user"
+	entry.TargetID = strconv.Itoa(target.ID)
+	entry.Details = details
+	// Impersonasi tanpa jejak audit tidak boleh terjadi
+	if err := s.audit.Record(c.UserContext(), entry); err != nil {
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{"error": "Gagal mencatat audit impersonasi"})
+	}
+	log.Printf("Admin %s mulai impersonasi user %s", admin.Username, target.Username)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"token":         token,
+			"expires_in":    int64(s.ttl.Seconds()),
+			"impersonating": target,
+			"impersonator":  model.ActorClaim{UserID: admin.ID, Username: admin.Username},
+		},
+	})
+}
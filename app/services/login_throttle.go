@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"tugas5/app/repository"
+	"tugas5/config"
+)
+
+// loginThrottle -> lacak login gagal per akun dan per alamat IP, lalu kunci
+// sementara dengan durasi yang berlipat dua setiap kegagalan setelah batas
+type loginThrottle struct {
+	repo repository.LoginThrottleRepository
+	cfg  config.AuthConfig
+}
+
+func userThrottleKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockedFor -> sisa waktu kunci untuk kombinasi username dan IP, 0 kalau boleh mencoba
+func (t *loginThrottle) lockedFor(ctx context.Context, username, ip string) (time.Duration, error) {
+	until, err := t.repo.LockedUntil(ctx, []string{userThrottleKey(username), ipThrottleKey(ip)})
+	if err != nil || until == nil {
+		return 0, err
+	}
+	return time.Until(*until), nil
+}
+
+// recordFailure -> username yang tidak terdaftar ikut dihitung supaya
+// perilaku kunci tidak membocorkan username mana yang ada
+func (t *loginThrottle) recordFailure(ctx context.Context, username, ip string) error {
+	if err := t.fail(ctx, userThrottleKey(username), t.cfg.MaxFailuresPerUser); err != nil {
+		return err
+	}
+	return t.fail(ctx, ipThrottleKey(ip), t.cfg.MaxFailuresPerIP)
+}
+
+// recordSuccess -> hanya hitungan akun yang direset; hitungan IP tetap supaya
+// satu akun valid tidak bisa dipakai menghapus jejak tebakan dari alamat itu
+func (t *loginThrottle) recordSuccess(ctx context.Context, username string) error {
+	_, err := t.repo.Reset(ctx, userThrottleKey(username))
+	return err
+}
+
+func (t *loginThrottle) fail(ctx context.Context, key string, threshold int) error {
+	failures, err := t.repo.RecordFailure(ctx, key, t.cfg.FailureWindow)
+	if err != nil || failures < threshold {
+		return err
+	}
+
+	lockFor := t.lockDuration(failures - threshold)
+	until := time.Now().Add(lockFor)
+	if err := t.repo.Lock(ctx, key, until); err != nil {
+		return err
+	}
+	log.Printf("Login dikunci: %s selama %s setelah %d kegagalan", key, lockFor, failures)
+	return nil
+}
+
+// lockDuration -> base * 2^n, dibatasi LockoutMax
+func (t *loginThrottle) lockDuration(n int) time.Duration {
+	d := float64(t.cfg.LockoutBase) * math.Pow(2, float64(n))
+	if d > float64(t.cfg.LockoutMax) {
+		return t.cfg.LockoutMax
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+	"tugas5/config"
+)
+
+func TestLockDuration(t *testing.T) {
+	throttle := &loginThrottle{cfg: config.AuthConfig{LockoutBase: time.Minute, LockoutMax: time.Hour}}
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{2, 4 * time.Minute},
+		{5, 32 * time.Minute},
+		{6, time.Hour}, // 64 menit dipotong ke LockoutMax
+		{7, time.Hour},
+		{62, time.Hour}, // 2^62 menit jauh melewati batas int64 nanodetik
+		{1100, time.Hour},
+		{math.MaxInt32, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := throttle.lockDuration(tt.n); got != tt.want {
+			t.Errorf("lockDuration(%d) = %s, seharusnya %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLockDurationMaxBelowBase(t *testing.T) {
+	// Konfigurasi aneh tetap tidak boleh mengunci lebih lama dari LockoutMax
+	throttle := &loginThrottle{cfg: config.AuthConfig{LockoutBase: time.Hour, LockoutMax: 10 * time.Minute}}
+	if got := throttle.lockDuration(0); got != 10*time.Minute {
+		t.Errorf("lockDuration(0) = %s, seharusnya 10m", got)
+	}
+}
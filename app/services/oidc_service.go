@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/config"
+	"tugas5/middleware"
+	"tugas5/utils"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateTTL    = 10 * time.Minute
+	oidcCallTimeout = 10 * time.Second
+
+	// oidcStateCookie -> state juga disimpan di cookie browser yang memulai login,
+	// supaya callback dengan code milik orang lain (login CSRF) ditolak
+	oidcStateCookie = "oidc_state"
+)
+
+var (
+	errOIDCUnknownUser = errors.New("akun SSO belum terdaftar")
+	errOIDCConflict    = errors.New("akun dengan email ini sudah terhubung ke akun SSO lain")
+	errOIDCUnverified  = errors.New("email akun SSO belum diverifikasi identity provider")
+
+	usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+// oidcClaims -> claim ID token yang dipakai untuk mencocokkan akun
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type OIDCService struct {
+	auth   *AuthService
+	users  repository.UserRepository
+	states repository.OIDCStateRepository
+	cfg    config.OIDCConfig
+
+	// Discovery baru dilakukan saat login SSO pertama, supaya server tetap
+	// bisa jalan (dengan login password) walaupun IdP sedang tidak bisa dihubungi
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCService(auth *AuthService, users repository.UserRepository, states repository.OIDCStateRepository, cfg config.OIDCConfig) *OIDCService {
+	return &OIDCService{auth: auth, users: users, states: states, cfg: cfg}
+}
+
+// GET /oidc/login
+func (s *OIDCService) LoginService(c *fiber.Ctx) error {
+	oauth, _, err := s.client()
+	if err != nil {
+		log.Println("Discovery OIDC gagal:", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Identity provider tidak bisa dihubungi"})
+	}
+
+	state, err := utils.GenerateRandomToken()
+	if err != nil {
+		return dbError(c, err)
+	}
+	nonce, err := utils.GenerateRandomToken()
+	if err != nil {
+		return dbError(c, err)
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	data := repository.OIDCLoginState{Nonce: nonce, CodeVerifier: verifier}
+	if err := s.states.Save(c.UserContext(), state, data, time.Now().Add(oidcStateTTL)); err != nil {
+		return dbError(c, err)
+	}
+	s.setStateCookie(c, state, oidcStateTTL)
+
+	return c.Redirect(oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), fiber.StatusFound)
+}
+
+// GET /oidc/callback
+func (s *OIDCService) CallbackService(c *fiber.Ctx) error {
+	if idpErr := c.Query("error"); idpErr != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Login SSO dibatalkan: " + idpErr,
+			"success": false,
+		})
+	}
+	if c.Query("state") == "" || c.Query("code") == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Parameter state dan code diperlukan",
+			"success": false,
+		})
+	}
+
+	// State hanya berlaku di browser yang memulai login
+	cookieState := c.Cookies(oidcStateCookie)
+	s.setStateCookie(c, "", -time.Second)
+	if subtle.ConstantTimeCompare([]byte(cookieState), []byte(c.Query("state"))) != 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "State login SSO tidak cocok dengan browser ini, silakan ulangi",
+			"success": false,
+		})
+	}
+
+	data, err := s.states.Consume(c.UserContext(), c.Query("state"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "State login SSO tidak valid atau kedaluwarsa, silakan ulangi",
+				"success": false,
+			})
+		}
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
+	}
+
+	oauth, verifier, err := s.client()
+	if err != nil {
+		log.Println("Discovery OIDC gagal:", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"message": "Identity provider tidak bisa dihubungi",
+			"success": false,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcCallTimeout)
+	defer cancel()
+
+	token, err := oauth.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(data.CodeVerifier))
+	if err != nil {
+		log.Println("Penukaran code OIDC gagal:", err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Login SSO gagal",
+			"success": false,
+		})
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != data.Nonce {
+		log.Println("ID token OIDC ditolak:", err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Login SSO gagal",
+			"success": false,
+		})
+	}
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Login SSO gagal",
+			"success": false,
+		})
+	}
+
+	user, err := s.resolveUser(c.UserContext(), idToken.Subject, claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, errOIDCUnknownUser):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Akun SSO Anda belum terdaftar di sistem ini, hubungi admin",
+				"success": false,
+			})
+		case errors.Is(err, errOIDCUnverified):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Email akun SSO Anda belum diverifikasi identity provider",
+				"success": false,
+			})
+		case errors.Is(err, errOIDCConflict):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": errOIDCConflict.Error(),
+				"success": false,
+			})
+		}
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
+	}
+	if !user.IsActive {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Akun Anda dinonaktifkan, hubungi admin",
+			"success": false,
+		})
+	}
+	// Aturan yang sama dengan login password
+	if user.EmailVerifiedAt == nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Email belum diverifikasi, cek email Anda",
+			"success": false,
+		})
+	}
+
+	// Kebijakan 2FA berlaku sama untuk login SSO maupun password
+	if user.TOTPEnabled || s.auth.cfg.Auth.MFARequired(user.Role) {
+		return s.auth.requireSecondFactor(c, *user)
+	}
+	response, err := s.auth.startSession(c, *user)
+	if err != nil {
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal membuat token",
+			"success": false,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// resolveUser -> cari akun lewat subject, lalu lewat email yang sudah
+// diverifikasi IdP, lalu (kalau diizinkan) buat akun baru
+func (s *OIDCService) resolveUser(ctx context.Context, subject string, claims oidcClaims) (*model.User, error) {
+	user, err := s.users.GetByOIDCSubject(ctx, subject)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return user, err
+	}
+
+	if claims.Email != "" && claims.EmailVerified {
+		user, err := s.users.GetByEmail(ctx, claims.Email)
+		if err == nil {
+			if err := s.users.SetOIDCSubject(ctx, user.ID, subject); err != nil {
+				if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repository.ErrUserConflict) {
+					return nil, errOIDCConflict
+				}
+				return nil, err
+			}
+			log.Printf("User %s dihubungkan ke subject OIDC %s", user.Username, subject)
+			return user, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	if !s.cfg.AutoCreate || claims.Email == "" {
+		return nil, errOIDCUnknownUser
+	}
+	if !claims.EmailVerified {
+		return nil, errOIDCUnverified
+	}
+	return s.createUser(ctx, subject, claims)
+}
+
+// createUser -> akun SSO diberi password acak; pemiliknya tetap bisa memakai
+// lupa password kalau suatu saat ingin login tanpa IdP
+func (s *OIDCService) createUser(ctx context.Context, subject string, claims oidcClaims) (*model.User, error) {
+	password, err := utils.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	base := oidcUsername(claims)
+	username := base
+	for attempt := 0; attempt < 3; attempt++ {
+		user, err := s.users.Create(ctx, model.CreateUserRequest{
+			Username: username,
+			Email:    claims.Email,
+			Role:     s.cfg.DefaultRole,
+		}, hash, true)
+		if errors.Is(err, repository.ErrUserConflict) {
+			suffix := make([]byte, 2)
+			if _, err := rand.Read(suffix); err != nil {
+				return nil, err
+			}
+			username = base + "-" + hex.EncodeToString(suffix)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := s.users.SetOIDCSubject(ctx, user.ID, subject); err != nil {
+			return nil, err
+		}
+		log.Printf("User %s dibuat otomatis dari login SSO (subject %s)", user.Username, subject)
+		return user, nil
+	}
+	return nil, errOIDCConflict
+}
+
+// setStateCookie -> SameSite Lax karena callback datang sebagai navigasi
+// dari domain IdP; maxAge negatif menghapus cookie
+func (s *OIDCService) setStateCookie(c *fiber.Ctx, state string, maxAge time.Duration) {
+	path := "/"
+	if redirect, err := url.Parse(s.cfg.RedirectURL); err == nil && redirect.Path != "" {
+		path = redirect.Path
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   strings.HasPrefix(s.cfg.RedirectURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func oidcUsername(claims oidcClaims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	name = usernameInvalidChars.ReplaceAllString(strings.ToLower(name), "")
+	if len(name) < 3 {
+		name = "sso-" + name
+	}
+	return name
+}
+
+// client -> discovery IdP sekali, hasilnya dipakai untuk semua login berikutnya
+func (s *OIDCService) client() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oauth != nil {
+		return s.oauth, s.verifier, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcCallTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, s.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.oauth = &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, s.cfg.Scopes...),
+	}
+	s.verifier = provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID})
+	return s.oauth, s.verifier, nil
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/cmd/mockidp/idp"
+	"tugas5/config"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const oidcTestRedirect = "http://alumni.test/api/oidc/callback"
+
+type fakeOIDCStates struct {
+	mu     sync.Mutex
+	states map[string]repository.OIDCLoginState
+}
+
+func (f *fakeOIDCStates) Save(ctx context.Context, state string, data repository.OIDCLoginState, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states[state] = data
+	return nil
+}
+
+func (f *fakeOIDCStates) Consume(ctx context.Context, state string) (*repository.OIDCLoginState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.states[state]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	delete(f.states, state)
+	return &data, nil
+}
+
+// oidcUsers -> user palsu di memori; hanya method yang dipakai login SSO,
+// method lain akan panic kalau terpanggil
+type oidcUsers struct {
+	repository.UserRepository
+	mu       sync.Mutex
+	users    []model.User
+	subjects map[string]int
+}
+
+func (f *oidcUsers) GetByOIDCSubject(ctx context.Context, subject string) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id, ok := f.subjects[subject]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	user := f.users[id-1]
+	return &user, nil
+}
+
+func (f *oidcUsers) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, email) {
+			return &u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *oidcUsers) Create(ctx context.Context, req model.CreateUserRequest, passwordHash string, verified bool) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user := model.User{ID: len(f.users) + 1, Username: req.Username, Email: req.Email, Role: req.Role, IsActive: true}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	f.users = append(f.users, user)
+	return &user, nil
+}
+
+func (f *oidcUsers) SetOIDCSubject(ctx context.Context, id int, subject string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if owner, ok := f.subjects[subject]; ok && owner != id {
+		return repository.ErrUserConflict
+	}
+	f.subjects[subject] = id
+	return nil
+}
+
+// oidcSessions -> hanya mencatat sesi yang dibuat setelah login berhasil
+type oidcSessions struct {
+	repository.SessionRepository
+	mu      sync.Mutex
+	created []model.Session
+}
+
+func (f *oidcSessions) Create(ctx context.Context, session *model.Session, refreshHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.created = append(f.created, *session)
+	return nil
+}
+
+type oidcTestEnv struct {
+	app      *fiber.App
+	users    *oidcUsers
+	sessions *oidcSessions
+}
+
+// newOIDCTestEnv -> API dengan login SSO yang diarahkan ke mock IdP di proses yang sama
+func newOIDCTestEnv(t *testing.T, emailVerified bool) *oidcTestEnv {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + ts.Listener.Addr().String()
+	server, err := idp.New(idp.Config{
+		Issuer:        issuer,
+		ClientID:      "alumni-api",
+		Email:         "dosen@kampus.ac.id",
+		EmailVerified: emailVerified,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Config.Handler = server.Handler()
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	cfg := testConfig(t)
+	cfg.JWT.RefreshTTL = time.Hour
+	cfg.OIDC = config.OIDCConfig{
+		IssuerURL:   issuer,
+		ClientID:    "alumni-api",
+		RedirectURL: oidcTestRedirect,
+		Scopes:      []string{"profile", "email"},
+		AutoCreate:  true,
+		DefaultRole: "user",
+	}
+	env := &oidcTestEnv{users: &oidcUsers{subjects: map[string]int{}}, sessions: &oidcSessions{}}
+	auth := NewAuthService(nil, env.users, env.sessions, nil, cfg)
+	svc := NewOIDCService(auth, env.users, &fakeOIDCStates{states: map[string]repository.OIDCLoginState{}}, cfg.OIDC)
+
+	env.app = fiber.New()
+	env.app.Get("/api/oidc/login", svc.LoginService)
+	env.app.Get("/api/oidc/callback", svc.CallbackService)
+	return env
+}
+
+// authorize -> mulai login, ikuti redirect ke IdP, dan kembalikan URL callback
+// beserta cookie state yang diberikan ke browser
+func (env *oidcTestEnv) authorize(t *testing.T) (callback *url.URL, stateCookie *http.Cookie) {
+	t.Helper()
+	resp, err := env.app.Test(httptest.NewRequest("GET", "/api/oidc/login", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("login: status %d", resp.StatusCode)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oidcStateCookie {
+			stateCookie = cookie
+		}
+	}
+	if stateCookie == nil || !stateCookie.HttpOnly || stateCookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("cookie state HttpOnly SameSite=Lax tidak diset: %v", resp.Header.Values("Set-Cookie"))
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	idpResp, err := client.Get(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idpResp.Body.Close()
+	callback, err = url.Parse(idpResp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(callback.String(), oidcTestRedirect) {
+		t.Fatalf("IdP tidak mengarahkan ke callback: %q (%v)", idpResp.Header.Get("Location"), err)
+	}
+	return callback, stateCookie
+}
+
+func (env *oidcTestEnv) callback(t *testing.T, callback *url.URL, cookie *http.Cookie) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := env.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestOIDCCallbackFlow(t *testing.T) {
+	env := newOIDCTestEnv(t, true)
+
+	callback, cookie := env.authorize(t)
+	status, body := env.callback(t, callback, cookie)
+	if status != 200 {
+		t.Fatalf("callback: status %d, body %s", status, body)
+	}
+	user, err := env.users.GetByOIDCSubject(nil, "mock-dosen@kampus.ac.id")
+	if err != nil {
+		t.Fatalf("akun SSO seharusnya dibuat otomatis: %v", err)
+	}
+	if user.Email != "dosen@kampus.ac.id" || user.EmailVerifiedAt == nil {
+		t.Errorf("akun SSO tidak sesuai: %+v", user)
+	}
+	if len(env.sessions.created) != 1 {
+		t.Errorf("seharusnya 1 sesi dibuat, ada %d", len(env.sessions.created))
+	}
+
+	// State sekali pakai
+	if status, _ := env.callback(t, callback, cookie); status != 400 {
+		t.Errorf("callback diulang: status %d, seharusnya 400", status)
+	}
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	env := newOIDCTestEnv(t, true)
+
+	// Callback dengan code milik penyerang dibuka di browser korban yang tidak
+	// pernah memulai login, atau yang memulai login dengan state lain
+	callback, _ := env.authorize(t)
+	if status, body := env.callback(t, callback, nil); status != 400 {
+		t.Errorf("tanpa cookie state: status %d, seharusnya 400 (%s)", status, body)
+	}
+	_, otherCookie := env.authorize(t)
+	if status, body := env.callback(t, callback, otherCookie); status != 400 {
+		t.Errorf("cookie state lain: status %d, seharusnya 400 (%s)", status, body)
+	}
+	if len(env.sessions.created) != 0 {
+		t.Errorf("tidak boleh ada sesi dibuat, ada %d", len(env.sessions.created))
+	}
+}
+
+func TestOIDCCallbackRejectsUnverifiedEmail(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+
+	callback, cookie := env.authorize(t)
+	if status, body := env.callback(t, callback, cookie); status != 403 {
+		t.Errorf("akun baru dengan email_verified=false: status %d, seharusnya 403 (%s)", status, body)
+	}
+
+	// Akun lokal yang belum verifikasi email tetap ditolak walaupun subject-nya cocok
+	user, _ := env.users.Create(nil, model.CreateUserRequest{
+		Username: "dosen", Email: "dosen@kampus.ac.id", Role: "user",
+	}, "", false)
+	if err := env.users.SetOIDCSubject(nil, user.ID, "mock-dosen@kampus.ac.id"); err != nil {
+		t.Fatal(err)
+	}
+	callback, cookie = env.authorize(t)
+	if status, body := env.callback(t, callback, cookie); status != 403 {
+		t.Errorf("akun belum verifikasi: status %d, seharusnya 403 (%s)", status, body)
+	}
+	if len(env.sessions.created) != 0 {
+		t.Errorf("tidak boleh ada sesi dibuat, ada %d", len(env.sessions.created))
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/config"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Permintaan reset diproses sejumlah worker tetap dengan antrean terbatas,
+// supaya banjir request /password/forgot tidak menumpuk goroutine dan koneksi SMTP
+const (
+	resetMailWorkers   = 4
+	resetMailQueueSize = 100
+)
+
+type PasswordResetService struct {
+	users  repository.CachedUserRepository
+	resets repository.PasswordResetRepository
+	uow    repository.UnitOfWork
+	mailer utils.Mailer
+	cfg    *config.Config
+	queue  chan string
+}
+
+func NewPasswordResetService(users repository.CachedUserRepository, resets repository.PasswordResetRepository,
+	uow repository.UnitOfWork, mailer utils.Mailer, cfg *config.Config) *PasswordResetService {
+	s := &PasswordResetService{users: users, resets: resets, uow: uow, mailer: mailer, cfg: cfg,
+		queue: make(chan string, resetMailQueueSize)}
+	for i := 0; i < resetMailWorkers; i++ {
+		go s.worker()
+	}
+	return s
+}
+
+func (s *PasswordResetService) worker() {
+	for email := range s.queue {
+		s.sendResetToken(email)
+	}
+}
+
+// POST /password/forgot
+func (s *PasswordResetService) ForgotService(c *fiber.Ctx) error {
+	var req model.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Email wajib diisi"})
+	}
+
+	// Dikerjakan di background supaya waktu respons sama, baik email terdaftar maupun tidak.
+	// Kalau antrean penuh permintaan dibuang; jawabannya tetap sama supaya tidak membocorkan apa pun.
+	select {
+	case s.queue <- strings.TrimSpace(req.Email):
+	default:
+		log.Println("Antrean email reset password penuh, permintaan dibuang")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Kalau email terdaftar, instruksi reset password sudah dikirim",
+	})
+}
+
+// POST /password/reset
+func (s *PasswordResetService) ResetService(c *fiber.Ctx) error {
+	var req model.ResetPasswordTokenRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Token reset wajib diisi"})
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		return c.Status(400).JSON(fiber.Map{"error": "Password minimal 8 karakter"})
+	}
+
+	hash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	// Token dipakai, password diganti, dan sesi dicabut bersama; kalau salah satu
+	// gagal, token tetap bisa dipakai lagi dan sesi lama tidak tertinggal aktif
+	var userID int
+	err = s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		var err error
+		if userID, err = tx.PasswordResets.Consume(c.UserContext(), utils.HashToken(req.Token)); err != nil {
+			return err
+		}
+		// Token diterima lewat email, berarti kepemilikan email sudah terbukti;
+		// token_version naik supaya access token yang sudah terbit ikut tidak berlaku
+		if err := tx.Users.ResetPassword(c.UserContext(), userID, hash); err != nil {
+			return err
+		}
+		return tx.Sessions.RevokeAllForUser(c.UserContext(), userID)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(400).JSON(fiber.Map{"error": "Token reset tidak valid atau sudah kedaluwarsa"})
+		}
+		return dbError(c, err)
+	}
+	// Perubahan lewat transaksi tidak melewati cache user
+	s.users.Invalidate(userID)
+
+	log.Printf("Password user %d direset lewat email, semua sesi dicabut", userID)
+	return c.JSON(fiber.Map{"success": true, "message": "Password berhasil direset, silakan login kembali"})
+}
+
+func (s *PasswordResetService) sendResetToken(email string) {
+	// Jalan di worker setelah response terkirim, jadi tidak boleh memakai
+	// context request yang sudah selesai
+	ctx := context.Background()
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println("Gagal mencari user untuk reset password:", err)
+		}
+		return
+	}
+	if !user.IsActive {
+		return
+	}
+
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		log.Println("Gagal membuat token reset password:", err)
+		return
+	}
+	ttl := s.cfg.Auth.PasswordResetTTL
+	if err := s.resets.Create(ctx, user.ID, utils.HashToken(token), time.Now().Add(ttl)); err != nil {
+		log.Println("Gagal menyimpan token reset password:", err)
+		return
+	}
+
+	err = s.mailer.Send(utils.Mail{
+		To:      user.Email,
+		Subject: "Reset password Sistem Alumni",
+		Body: fmt.Sprintf("Halo %s,\n\n"+
+			"Kami menerima permintaan reset password untuk akun Anda.\n"+
+			"Token reset: %s\n\n"+
+			"Kirim token ini bersama password baru ke %s%s/api/password/reset.\n"+
+			"Token hanya bisa dipakai sekali dan berlaku %s.\n"+
+			"Abaikan email ini kalau Anda tidak meminta reset password.\n",
+			user.Username, token, s.cfg.App.BaseURL, s.cfg.App.BasePath, ttl),
+	})
+	if err != nil {
+		log.Printf("Gagal mengirim email reset password ke user %d: %v", user.ID, err)
+	}
+}
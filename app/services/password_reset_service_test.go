@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/config"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var resetTokenPattern = regexp.MustCompile(`Token reset: (\S+)`)
+
+// resetState -> isi "database" palsu untuk flow reset password: satu user,
+// token reset, dan sesi yang dicabut. Unit of work palsu mengembalikan
+// snapshot-nya kalau fn gagal, seperti rollback.
+type resetState struct {
+	mu sync.Mutex
+	resetData
+	failRevoke bool
+}
+
+type resetData struct {
+	user       model.User
+	hash       string
+	tokens     map[string]time.Time
+	revokedAll []int
+}
+
+func (st *resetState) snapshot() resetData {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	snap := st.resetData
+	snap.tokens = maps.Clone(st.tokens)
+	return snap
+}
+
+func (st *resetState) restore(snap resetData) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.resetData = snap
+}
+
+type resetUsers struct {
+	repository.CachedUserRepository
+	st *resetState
+}
+
+func (f resetUsers) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	f.st.mu.Lock()
+	defer f.st.mu.Unlock()
+	if !strings.EqualFold(f.st.user.Email, email) {
+		return nil, sql.ErrNoRows
+	}
+	user := f.st.user
+	return &user, nil
+}
+
+func (f resetUsers) ResetPassword(ctx context.Context, id int, passwordHash string) error {
+	f.st.mu.Lock()
+	defer f.st.mu.Unlock()
+	if id != f.st.user.ID {
+		return sql.ErrNoRows
+	}
+	f.st.hash = passwordHash
+	f.st.user.MustChangePassword = false
+	if f.st.user.EmailVerifiedAt == nil {
+		now := time.Now()
+		f.st.user.EmailVerifiedAt = &now
+	}
+	f.st.user.TokenVersion++
+	return nil
+}
+
+func (f resetUsers) Invalidate(id int) {}
+
+type resetTokens struct {
+	repository.PasswordResetRepository
+	st *resetState
+}
+
+func (f resetTokens) Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	f.st.mu.Lock()
+	defer f.st.mu.Unlock()
+	f.st.tokens[tokenHash] = expiresAt
+	return nil
+}
+
+func (f resetTokens) Consume(ctx context.Context, tokenHash string) (int, error) {
+	f.st.mu.Lock()
+	defer f.st.mu.Unlock()
+	expiresAt, ok := f.st.tokens[tokenHash]
+	if !ok || time.Now().After(expiresAt) {
+		return 0, sql.ErrNoRows
+	}
+	delete(f.st.tokens, tokenHash)
+	return f.st.user.ID, nil
+}
+
+type resetSessions struct {
+	repository.SessionRepository
+	st *resetState
+}
+
+func (f resetSessions) RevokeAllForUser(ctx context.Context, userID int) error {
+	f.st.mu.Lock()
+	defer f.st.mu.Unlock()
+	if f.st.failRevoke {
+		return errors.New("koneksi database terputus")
+	}
+	f.st.revokedAll = append(f.st.revokedAll, userID)
+	return nil
+}
+
+type resetUnitOfWork struct {
+	st *resetState
+}
+
+func (u resetUnitOfWork) Do(ctx context.Context, fn func(tx repository.Repositories) error) error {
+	snap := u.st.snapshot()
+	err := fn(repository.Repositories{
+		Users:          resetUsers{st: u.st},
+		PasswordResets: resetTokens{st: u.st},
+		Sessions:       resetSessions{st: u.st},
+	})
+	if err != nil {
+		u.st.restore(snap)
+	}
+	return err
+}
+
+func newPasswordResetApp(t *testing.T) (*fiber.App, *resetState, string) {
+	t.Helper()
+	cfg := testConfig(t)
+	app, st := newPasswordResetAppWithMailer(t, cfg, utils.NewMailer(cfg.Mail))
+	return app, st, cfg.Mail.FileDir
+}
+
+func newPasswordResetAppWithMailer(t *testing.T, cfg *config.Config, mailer utils.Mailer) (*fiber.App, *resetState) {
+	t.Helper()
+	cfg.Auth.PasswordResetTTL = time.Hour
+	hash, err := utils.HashPassword("password-lama")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &resetState{resetData: resetData{
+		user:   model.User{ID: 1, Username: "budi", Email: "budi@alumni.example.ac.id", Role: "user", IsActive: true},
+		hash:   hash,
+		tokens: map[string]time.Time{},
+	}}
+	svc := NewPasswordResetService(resetUsers{st: st}, resetTokens{st: st}, resetUnitOfWork{st: st}, mailer, cfg)
+
+	app := fiber.New()
+	app.Post("/api/password/forgot", svc.ForgotService)
+	app.Post("/api/password/reset", svc.ResetService)
+	return app, st
+}
+
+// waitForMail -> email yang dikirim di background ditunggu sampai muncul
+func waitForMail(t *testing.T, dir string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mails := outbox(t, dir)
+		if len(mails) >= n {
+			return mails
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("menunggu %d email, baru ada %d", n, len(mails))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// requestResetToken -> minta reset lewat /password/forgot dan ambil token dari email
+func requestResetToken(t *testing.T, app *fiber.App, mailDir string) string {
+	t.Helper()
+	status, body := doJSON(t, app, "POST", "/api/password/forgot", `{"email":"Budi@alumni.example.ac.id"}`)
+	if status != 200 {
+		t.Fatalf("forgot: status %d, body %s", status, body)
+	}
+	mail := waitForMail(t, mailDir, 1)[0]
+	if !strings.Contains(mail, "To: budi@alumni.example.ac.id") {
+		t.Errorf("email dikirim ke alamat yang salah:\n%s", mail)
+	}
+	match := resetTokenPattern.FindStringSubmatch(mail)
+	if match == nil {
+		t.Fatalf("token reset tidak ditemukan di email:\n%s", mail)
+	}
+	return match[1]
+}
+
+// blockingMailer -> menahan setiap pengiriman sampai release ditutup
+type blockingMailer struct {
+	release chan struct{}
+	sent    atomic.Int32
+}
+
+func (m *blockingMailer) Send(utils.Mail) error {
+	<-m.release
+	m.sent.Add(1)
+	return nil
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	app, st, mailDir := newPasswordResetApp(t)
+	token := requestResetToken(t, app, mailDir)
+
+	status, _ := doJSON(t, app, "POST", "/api/password/reset", `{"token":"`+token+`","new_password":"pendek"}`)
+	if status != 400 {
+		t.Errorf("password terlalu pendek: status %d, seharusnya 400", status)
+	}
+
+	status, body := doJSON(t, app, "POST", "/api/password/reset", `{"token":"`+token+`","new_password":"password-baru"}`)
+	if status != 200 {
+		t.Fatalf("reset: status %d, body %s", status, body)
+	}
+	if !utils.CheckPassword("password-baru", st.hash) {
+		t.Error("password seharusnya sudah diganti")
+	}
+	if st.user.EmailVerifiedAt == nil {
+		t.Error("token dari email seharusnya sekaligus memverifikasi email")
+	}
+	if st.user.TokenVersion != 1 {
+		t.Errorf("token_version seharusnya naik supaya access token lama tidak berlaku, dapat %d", st.user.TokenVersion)
+	}
+	if len(st.revokedAll) != 1 || st.revokedAll[0] != 1 {
+		t.Errorf("semua sesi user 1 seharusnya dicabut, dapat %v", st.revokedAll)
+	}
+
+	// Token hanya sekali pakai
+	status, _ = doJSON(t, app, "POST", "/api/password/reset", `{"token":"`+token+`","new_password":"password-lain"}`)
+	if status != 400 {
+		t.Errorf("token dipakai ulang: status %d, seharusnya 400", status)
+	}
+}
+
+func TestPasswordResetRollsBackWhenRevokeFails(t *testing.T) {
+	app, st, mailDir := newPasswordResetApp(t)
+	token := requestResetToken(t, app, mailDir)
+
+	st.failRevoke = true
+	status, _ := doJSON(t, app, "POST", "/api/password/reset", `{"token":"`+token+`","new_password":"password-baru"}`)
+	if status != 500 {
+		t.Fatalf("pencabutan sesi gagal: status %d, seharusnya 500", status)
+	}
+	if !utils.CheckPassword("password-lama", st.hash) || st.user.TokenVersion != 0 {
+		t.Error("password tidak boleh berganti kalau sesi lama gagal dicabut")
+	}
+
+	// Token belum terpakai, jadi user cukup mencoba lagi
+	st.failRevoke = false
+	status, body := doJSON(t, app, "POST", "/api/password/reset", `{"token":"`+token+`","new_password":"password-baru"}`)
+	if status != 200 {
+		t.Fatalf("reset ulang: status %d, body %s", status, body)
+	}
+	if !utils.CheckPassword("password-baru", st.hash) || len(st.revokedAll) != 1 {
+		t.Error("reset ulang seharusnya mengganti password dan mencabut sesi")
+	}
+}
+
+func TestForgotPasswordQueueIsBounded(t *testing.T) {
+	mailer := &blockingMailer{release: make(chan struct{})}
+	app, _ := newPasswordResetAppWithMailer(t, testConfig(t), mailer)
+
+	// Jauh melebihi kapasitas antrean selama SMTP macet; tiap request tetap
+	// dijawab sama tanpa menunggu email terkirim
+	for i := 0; i < resetMailQueueSize*3; i++ {
+		status, body := doJSON(t, app, "POST", "/api/password/forgot", `{"email":"budi@alumni.example.ac.id"}`)
+		if status != 200 {
+			t.Fatalf("forgot ke-%d: status %d, body %s", i, status, body)
+		}
+	}
+
+	// Hanya yang sudah diambil worker dan yang masih muat di antrean yang terkirim
+	close(mailer.release)
+	minSent, maxSent := int32(resetMailQueueSize), int32(resetMailWorkers+resetMailQueueSize)
+	deadline := time.Now().Add(5 * time.Second)
+	for mailer.sent.Load() < minSent && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if sent := mailer.sent.Load(); sent < minSent || sent > maxSent {
+		t.Errorf("email terkirim %d, seharusnya antara %d dan %d (kapasitas antrean + worker)", sent, minSent, maxSent)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	app, _, mailDir := newPasswordResetApp(t)
+
+	// Jawaban sama seperti email terdaftar, tapi tidak ada email yang dikirim
+	status, body := doJSON(t, app, "POST", "/api/password/forgot", `{"email":"tidak.ada@example.com"}`)
+	if status != 200 {
+		t.Fatalf("forgot: status %d, body %s", status, body)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := len(outbox(t, mailDir)); n != 0 {
+		t.Errorf("seharusnya tidak ada email terkirim, ada %d", n)
+	}
+}
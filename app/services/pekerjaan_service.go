@@ -2,22 +2,27 @@ package services
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
 	"tugas5/app/model"
 	"tugas5/app/repository"
+	"tugas5/middleware"
 	"tugas5/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type PekerjaanService struct {
-	repo repository.PekerjaanRepository
+	repo    repository.PekerjaanRepository
+	uow     repository.UnitOfWork
+	changes *ChangeLog
 }
 
-func NewPekerjaanService(repo repository.PekerjaanRepository) *PekerjaanService {
-	return &PekerjaanService{repo: repo}
+func NewPekerjaanService(repo repository.PekerjaanRepository, uow repository.UnitOfWork, changes *ChangeLog) *PekerjaanService {
+	return &PekerjaanService{repo: repo, uow: uow, changes: changes}
 }
 
 // GET /pekerjaan?page=&limit=&sortBy=&order=&search=
@@ -39,9 +44,9 @@ func (s *PekerjaanService) GetAllService(c *fiber.Ctx) error {
 		order = "asc"
 	}
 
-	pekerjaan, err := s.repo.GetAll(search, sortBy, order, limit, offset)
+	pekerjaan, err := s.repo.GetAll(c.UserContext(), search, sortBy, order, limit, offset)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
 
 	response := fiber.Map{
@@ -63,12 +68,12 @@ func (s *PekerjaanService) GetByIDService(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
 	}
-	data, err := s.repo.GetByID(id)
+	data, err := s.repo.GetByID(c.UserContext(), id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{"error": "Pekerjaan tidak ditemukan"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "data": data})
 }
@@ -79,9 +84,9 @@ func (s *PekerjaanService) GetByAlumniIDService(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Alumni ID tidak valid"})
 	}
-	data, err := s.repo.GetByAlumniID(alumniID)
+	data, err := s.repo.GetByAlumniID(c.UserContext(), alumniID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "data": data})
 }
@@ -93,35 +98,32 @@ func (s *PekerjaanService) CreateService(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
 	}
 
-	role := c.Locals("role").(string)
-	userID := c.Locals("user_id").(int)
 	username := c.Locals("username").(string)
 
-	if role != "admin" {
-		req.AlumniID = userID
+	if !middleware.HasPermission(c, "pekerjaan:manage") {
+		alumniID, ok := middleware.CurrentAlumniID(c)
+		if !ok {
+			return c.Status(403).JSON(fiber.Map{"error": "Akun Anda belum terhubung dengan data alumni"})
+		}
+		req.AlumniID = alumniID
 	}
 
 	req.CreatedBy = utils.StringPtr(username)
 
-	if req.TanggalMulaiKerja != "" {
-		t, err := time.Parse("2006-01-02", req.TanggalMulaiKerja)
-		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Format tanggal mulai salah"})
-		}
-		req.TanggalMulaiKerja = t.Format("2006-01-02")
-	}
-	if req.TanggalSelesaiKerja != nil && *req.TanggalSelesaiKerja != "" {
-		t, err := time.Parse("2006-01-02", *req.TanggalSelesaiKerja)
-		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Format tanggal selesai salah"})
-		}
-		formatted := t.Format("2006-01-02")
-		req.TanggalSelesaiKerja = &formatted
+	if msg := normalizeTanggalPekerjaan(&req.TanggalMulaiKerja, req.TanggalSelesaiKerja); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
 	}
 
-	data, err := s.repo.Create(req)
+	var data *model.Pekerjaan
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		var err error
+		if data, err = tx.Pekerjaan.Create(c.UserContext(), req); err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "pekerjaan.create", "pekerjaan", data.ID, nil, data)
+	})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "data": data})
 }
@@ -134,28 +136,33 @@ func (s *PekerjaanService) UpdateService(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
 	}
 
-	if req.TanggalMulaiKerja != "" {
-		t, err := time.Parse("2006-01-02", req.TanggalMulaiKerja)
-		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Format tanggal mulai salah"})
-		}
-		req.TanggalMulaiKerja = t.Format("2006-01-02")
+	if msg := normalizeTanggalPekerjaan(&req.TanggalMulaiKerja, req.TanggalSelesaiKerja); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
 	}
-	if req.TanggalSelesaiKerja != nil && *req.TanggalSelesaiKerja != "" {
-		t, err := time.Parse("2006-01-02", *req.TanggalSelesaiKerja)
+
+	// Data di trash tidak bisa diubah sebelum direstore
+	var data *model.Pekerjaan
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		before, err := lockActivePekerjaan(c, tx, id)
 		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Format tanggal selesai salah"})
+			return err
 		}
-		formatted := t.Format("2006-01-02")
-		req.TanggalSelesaiKerja = &formatted
-	}
-
-	data, err := s.repo.Update(id, req)
+		if !canAccessPekerjaan(c, before) {
+			return errPekerjaanForbidden
+		}
+		if data, err = tx.Pekerjaan.Update(c.UserContext(), id, req); err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "pekerjaan.update", "pekerjaan", id, before, data)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{"error": "Pekerjaan tidak ditemukan"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		if err == errPekerjaanForbidden {
+			return forbiddenPekerjaan(c)
+		}
+		return dbError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "data": data})
 }
@@ -163,28 +170,32 @@ func (s *PekerjaanService) UpdateService(c *fiber.Ctx) error {
 // DELETE /pekerjaan/:id
 func (s *PekerjaanService) DeleteService(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
-	role := c.Locals("role").(string)
-	username := c.Locals("username").(string)
 
-	pekerjaan, err := s.repo.GetByID(id)
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		pekerjaan, err := lockActivePekerjaan(c, tx, id)
+		if err != nil {
+			return err
+		}
+		if !canAccessPekerjaan(c, pekerjaan) {
+			return errPekerjaanForbidden
+		}
+		if err := tx.Pekerjaan.Delete(c.UserContext(), id); err != nil {
+			return err
+		}
+		after := *pekerjaan
+		after.IsDeleted = true
+		return s.changes.In(tx).Record(c, "pekerjaan.delete", "pekerjaan", id, pekerjaan, after)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{"error": "Pekerjaan tidak ditemukan"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	if role != "admin" && (pekerjaan.CreatedBy == nil || *pekerjaan.CreatedBy != username) {
-		return c.Status(403).JSON(fiber.Map{
-			"error": "Anda tidak memiliki izin untuk menghapus pekerjaan ini",
-		})
-	}
-
-	if err := s.repo.Delete(id); err != nil {
-		if err == sql.ErrNoRows {
-			return c.Status(404).JSON(fiber.Map{"error": "Pekerjaan tidak ditemukan"})
+		if err == errPekerjaanForbidden {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Anda tidak memiliki izin untuk menghapus pekerjaan ini",
+			})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -197,81 +208,180 @@ func (s *PekerjaanService) DeleteService(c *fiber.Ctx) error {
 func (s *PekerjaanService) RestoreService(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	roleVal := c.Locals("role")
-	usernameVal := c.Locals("username")
-
-	if roleVal == nil || usernameVal == nil {
-		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	// Status trash dan kepemilikan dicek pada baris yang sudah dikunci supaya
+	// snapshot audit sama dengan yang direstore
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		before, err := tx.Pekerjaan.GetByIDForUpdate(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		if !before.IsDeleted {
+			return errPekerjaanNotDeleted
+		}
+		if !canAccessPekerjaan(c, before) {
+			return errPekerjaanForbidden
+		}
+		if err := tx.Pekerjaan.Restore(c.UserContext(), id); err != nil {
+			return err
+		}
+		after, err := tx.Pekerjaan.GetByID(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "pekerjaan.restore", "pekerjaan", id, before, after)
+	})
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return c.Status(404).JSON(fiber.Map{"error": "Data tidak ditemukan"})
+		case errPekerjaanNotDeleted:
+			return c.Status(400).JSON(fiber.Map{"error": "Data belum dihapus"})
+		case errPekerjaanForbidden:
+			return c.Status(403).JSON(fiber.Map{"error": "Anda tidak berhak restore data ini"})
+		}
+		return dbError(c, err)
 	}
-	role := roleVal.(string)
-	username := usernameVal.(string)
+	return c.JSON(fiber.Map{"success": true, "message": "Data berhasil direstore"})
+}
 
-	createdBy, isDeleted, err := s.repo.GetDeletedInfo(id)
+// DELETE /pekerjaan/hard-delete/:id
+func (s *PekerjaanService) HardDeleteService(c *fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	// Hard delete berlaku untuk data aktif maupun yang sudah di trash
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		before, err := tx.Pekerjaan.GetByIDForUpdate(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Pekerjaan.HardDelete(c.UserContext(), id); err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "pekerjaan.hard_delete", "pekerjaan", id, before, nil)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{"error": "Data tidak ditemukan"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
+	return c.JSON(fiber.Map{"success": true, "message": "Data dihapus permanen"})
+}
 
-	if !isDeleted {
-		return c.Status(400).JSON(fiber.Map{"error": "Data belum dihapus"})
+// GET /pekerjaan/:id/history
+func (s *PekerjaanService) HistoryService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
 	}
-	if role != "admin" && createdBy != username {
-		return c.Status(403).JSON(fiber.Map{"error": "Anda tidak berhak restore data ini"})
+	// Tanpa pekerjaan:manage, riwayat hanya bisa dilihat untuk pekerjaan milik
+	// sendiri yang masih ada (aktif maupun di trash)
+	if !middleware.HasPermission(c, "pekerjaan:manage") {
+		pekerjaan, err := s.getIncludingTrash(c, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return c.Status(404).JSON(fiber.Map{"error": "Pekerjaan tidak ditemukan"})
+			}
+			return dbError(c, err)
+		}
+		if !canAccessPekerjaan(c, pekerjaan) {
+			return forbiddenPekerjaan(c)
+		}
 	}
-
-	if err := s.repo.Restore(id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	revisions, err := s.changes.History(c.UserContext(), "pekerjaan", id)
+	if err != nil {
+		return dbError(c, err)
+	}
+	// Pekerjaan lama yang belum pernah diubah memang belum punya revisi
+	if len(revisions) == 0 {
+		if _, _, err := s.repo.GetDeletedInfo(c.UserContext(), id); err != nil {
+			if err == sql.ErrNoRows {
+				return c.Status(404).JSON(fiber.Map{"error": "Pekerjaan tidak ditemukan"})
+			}
+			return dbError(c, err)
+		}
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "Data berhasil direstore"})
+	return c.JSON(fiber.Map{"success": true, "data": revisions})
 }
 
-// DELETE /pekerjaan/hard-delete/:id
-func (s *PekerjaanService) HardDeleteService(c *fiber.Ctx) error {
-	id, _ := strconv.Atoi(c.Params("id"))
+// POST /pekerjaan/:id/revert/:revision
+func (s *PekerjaanService) RevertService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	revision, err := strconv.Atoi(c.Params("revision"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Nomor revisi tidak valid"})
+	}
 
-	roleVal := c.Locals("role")
-	if roleVal == nil {
-		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	rev, err := s.changes.Revision(c.UserContext(), "pekerjaan", id, revision)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": "Revisi tidak ditemukan"})
+		}
+		return dbError(c, err)
+	}
+	var snapshot model.Pekerjaan
+	if err := json.Unmarshal(rev.Data, &snapshot); err != nil {
+		return dbError(c, err)
 	}
-	role := roleVal.(string)
 
-	if role != "admin" {
-		return c.Status(403).JSON(fiber.Map{"error": "Hanya admin yang dapat melakukan hard delete"})
+	req := model.UpdatePekerjaanRequest{
+		NamaPerusahaan:     snapshot.NamaPerusahaan,
+		PosisiJabatan:      snapshot.PosisiJabatan,
+		BidangIndustri:     snapshot.BidangIndustri,
+		LokasiKerja:        snapshot.LokasiKerja,
+		GajiRange:          snapshot.GajiRange,
+		TanggalMulaiKerja:  snapshot.TanggalMulaiKerja.Format("2006-01-02"),
+		StatusPekerjaan:    &snapshot.StatusPekerjaan,
+		DeskripsiPekerjaan: snapshot.DeskripsiPekerjaan,
+	}
+	if snapshot.TanggalSelesaiKerja != nil {
+		selesai := snapshot.TanggalSelesaiKerja.Format("2006-01-02")
+		req.TanggalSelesaiKerja = &selesai
 	}
 
-	if err := s.repo.HardDelete(id); err != nil {
+	// Revert hanya mengembalikan isi data; pekerjaan di trash harus direstore dulu
+	var data *model.Pekerjaan
+	err = s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		before, err := lockActivePekerjaan(c, tx, id)
+		if err != nil {
+			return err
+		}
+		if data, err = tx.Pekerjaan.Update(c.UserContext(), id, req); err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "pekerjaan.revert", "pekerjaan", id, before, data)
+	})
+	if err != nil {
 		if err == sql.ErrNoRows {
-			return c.Status(404).JSON(fiber.Map{"error": "Data tidak ditemukan"})
+			return c.Status(404).JSON(fiber.Map{"error": "Pekerjaan tidak ditemukan atau masih di trash"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
-	return c.JSON(fiber.Map{"success": true, "message": "Data dihapus permanen"})
+	return c.JSON(fiber.Map{"success": true, "data": data, "reverted_to": revision})
 }
 
 // GET /pekerjaan/trash
-	func (s *PekerjaanService) GetTrashService(c *fiber.Ctx) error {
-	roleVal := c.Locals("role")
-	usernameVal := c.Locals("username")
-
-	if roleVal == nil || usernameVal == nil {
-		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+func (s *PekerjaanService) GetTrashService(c *fiber.Ctx) error {
+	// Tanpa pekerjaan:manage, trash hanya berisi pekerjaan alumni yang terhubung ke akun
+	all := middleware.HasPermission(c, "pekerjaan:manage")
+	alumniID, linked := middleware.CurrentAlumniID(c)
+	if !all && !linked {
+		return c.Status(403).JSON(fiber.Map{"error": "Akun Anda belum terhubung dengan data alumni"})
 	}
 
-	role := roleVal.(string)
-	username := usernameVal.(string)
-
-	data, err := s.repo.GetTrash(role, username)
+	data, err := s.repo.GetTrash(c.UserContext(), all, alumniID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
 	}
 
 	return c.JSON(fiber.Map{"success": true, "data": data})
 }
 
 // GET /pekerjaan/trash/:id
-	func (s *PekerjaanService) GetTrashByIDService(c *fiber.Ctx) error {
+func (s *PekerjaanService) GetTrashByIDService(c *fiber.Ctx) error {
 	idStr := c.Params("id")
 	if idStr == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "ID diperlukan"})
@@ -281,13 +391,79 @@ func (s *PekerjaanService) HardDeleteService(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
 	}
 
-	data, err := s.repo.GetByIDFromTrash(id)
+	data, err := s.repo.GetByIDFromTrash(c.UserContext(), id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{"error": "Data tidak ditemukan di trash"})
 		}
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return dbError(c, err)
+	}
+	if !canAccessPekerjaan(c, data) {
+		return forbiddenPekerjaan(c)
 	}
 
 	return c.JSON(fiber.Map{"success": true, "data": data})
 }
+
+// Dikembalikan dari dalam unit of work supaya transaksi dibatalkan dan handler
+// menjawab 403 (milik alumni lain) atau 400 (restore data yang belum dihapus)
+var (
+	errPekerjaanForbidden  = errors.New("pekerjaan milik alumni lain")
+	errPekerjaanNotDeleted = errors.New("pekerjaan belum dihapus")
+)
+
+// canAccessPekerjaan -> user tanpa pekerjaan:manage hanya boleh menyentuh
+// pekerjaan milik alumni yang terhubung ke akunnya
+func canAccessPekerjaan(c *fiber.Ctx, p *model.Pekerjaan) bool {
+	if middleware.HasPermission(c, "pekerjaan:manage") {
+		return true
+	}
+	own, ok := middleware.CurrentAlumniID(c)
+	return ok && own == p.AlumniID
+}
+
+func forbiddenPekerjaan(c *fiber.Ctx) error {
+	return c.Status(403).JSON(fiber.Map{"error": "Anda hanya dapat mengakses data pekerjaan milik sendiri"})
+}
+
+// getIncludingTrash -> pekerjaan aktif atau yang sudah di trash
+func (s *PekerjaanService) getIncludingTrash(c *fiber.Ctx, id int) (*model.Pekerjaan, error) {
+	p, err := s.repo.GetByID(c.UserContext(), id)
+	if err == sql.ErrNoRows {
+		p, err = s.repo.GetByIDFromTrash(c.UserContext(), id)
+	}
+	return p, err
+}
+
+// lockActivePekerjaan -> kunci pekerjaan yang belum di trash di dalam unit of work;
+// data di trash dianggap tidak ada
+func lockActivePekerjaan(c *fiber.Ctx, tx repository.Repositories, id int) (*model.Pekerjaan, error) {
+	p, err := tx.Pekerjaan.GetByIDForUpdate(c.UserContext(), id)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsDeleted {
+		return nil, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+// normalizeTanggalPekerjaan -> tanggal mulai/selesai diseragamkan ke YYYY-MM-DD;
+// hasilnya pesan error untuk klien, kosong kalau valid
+func normalizeTanggalPekerjaan(mulai *string, selesai *string) string {
+	if *mulai != "" {
+		t, err := time.Parse("2006-01-02", *mulai)
+		if err != nil {
+			return "Format tanggal mulai salah"
+		}
+		*mulai = t.Format("2006-01-02")
+	}
+	if selesai != nil && *selesai != "" {
+		t, err := time.Parse("2006-01-02", *selesai)
+		if err != nil {
+			return "Format tanggal selesai salah"
+		}
+		*selesai = t.Format("2006-01-02")
+	}
+	return ""
+}
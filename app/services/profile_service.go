@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/middleware"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// minPasswordLength -> panjang minimum password baru
+const minPasswordLength = 8
+
+type ProfileService struct {
+	users    repository.CachedUserRepository
+	alumni   repository.AlumniRepository
+	sessions repository.SessionRepository
+	uow      repository.UnitOfWork
+	changes  *ChangeLog
+}
+
+func NewProfileService(users repository.CachedUserRepository, alumni repository.AlumniRepository, sessions repository.SessionRepository,
+	uow repository.UnitOfWork, changes *ChangeLog) *ProfileService {
+	return &ProfileService{users: users, alumni: alumni, sessions: sessions, uow: uow, changes: changes}
+}
+
+// GET /profile
+func (s *ProfileService) GetProfileService(c *fiber.Ctx) error {
+	profile, err := s.loadProfile(c.UserContext(), c.Locals("user_id").(int))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(404).JSON(fiber.Map{"error": "User tidak ditemukan"})
+		}
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": profile})
+}
+
+// PUT /profile
+func (s *ProfileService) UpdateProfileService(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req model.UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
+	}
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if _, err := mail.ParseAddress(email); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Format email tidak valid"})
+		}
+		req.Email = &email
+	}
+
+	alumniID, linked := middleware.CurrentAlumniID(c)
+	if !linked && (req.NoTelepon != nil || req.Alamat != nil) {
+		return c.Status(400).JSON(fiber.Map{"error": "Akun Anda belum terhubung dengan data alumni"})
+	}
+
+	// Email akun, email kontak alumni, dan jejak audit-nya disimpan bersama
+	err := s.uow.Do(c.UserContext(), func(tx repository.Repositories) error {
+		if req.Email != nil {
+			if err := tx.Users.UpdateEmail(c.UserContext(), userID, *req.Email); err != nil {
+				return err
+			}
+		}
+		if !linked {
+			return nil
+		}
+		before, err := tx.Alumni.GetByIDForUpdate(c.UserContext(), alumniID)
+		if err != nil {
+			return err
+		}
+		after, err := tx.Alumni.UpdateContact(c.UserContext(), alumniID, req)
+		if err != nil {
+			return err
+		}
+		return s.changes.In(tx).Record(c, "alumni.update", "alumni", alumniID, before, after)
+	})
+	// Perubahan lewat transaksi tidak melewati cache user
+	s.users.Invalidate(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return c.Status(409).JSON(fiber.Map{"error": "Email sudah dipakai akun lain"})
+		}
+		return dbError(c, err)
+	}
+
+	profile, err := s.loadProfile(c.UserContext(), userID)
+	if err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": profile})
+}
+
+// POST /profile/password
+func (s *ProfileService) ChangePasswordService(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req model.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
+	}
+	if req.OldPassword == "" || len(req.NewPassword) < minPasswordLength {
+		return c.Status(400).JSON(fiber.Map{"error": "Password lama wajib diisi dan password baru minimal 8 karakter"})
+	}
+
+	hash, err := s.users.GetPasswordHash(c.UserContext(), userID)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !utils.CheckPassword(req.OldPassword, hash) {
+		return c.Status(400).JSON(fiber.Map{"error": "Password lama salah"})
+	}
+
+	newHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if err := s.users.UpdatePassword(c.UserContext(), userID, newHash); err != nil {
+		return dbError(c, err)
+	}
+
+	// Sesi di perangkat lain dicabut, sesi yang dipakai sekarang tetap jalan
+	sessionID, _ := c.Locals("session_id").(string)
+	if err := s.sessions.RevokeOthers(c.UserContext(), userID, sessionID); err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Password berhasil diubah"})
+}
+
+func (s *ProfileService) loadProfile(ctx context.Context, userID int) (*model.ProfileResponse, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	profile := &model.ProfileResponse{User: *user}
+	if user.AlumniID != nil {
+		alumni, err := s.alumni.GetByID(ctx, *user.AlumniID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		profile.Alumni = alumni
+	}
+	return profile, nil
+}
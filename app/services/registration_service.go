@@ -0,0 +1,155 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/config"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	purposeVerifyEmail   = "verify_email"
+	emailVerificationTTL = 24 * time.Hour
+)
+
+type RegistrationService struct {
+	users  repository.UserRepository
+	alumni repository.AlumniRepository
+	mailer utils.Mailer
+	cfg    *config.Config
+}
+
+func NewRegistrationService(users repository.UserRepository, alumni repository.AlumniRepository, mailer utils.Mailer, cfg *config.Config) *RegistrationService {
+	return &RegistrationService{users: users, alumni: alumni, mailer: mailer, cfg: cfg}
+}
+
+// POST /register
+func (s *RegistrationService) RegisterService(c *fiber.Ctx) error {
+	var req model.RegisterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
+	}
+	req.NIM = strings.TrimSpace(req.NIM)
+	req.Email = strings.TrimSpace(req.Email)
+	req.Username = strings.TrimSpace(req.Username)
+
+	if req.NIM == "" || len(req.Username) < 3 {
+		return c.Status(400).JSON(fiber.Map{"error": "NIM wajib diisi dan username minimal 3 karakter"})
+	}
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Format email tidak valid"})
+	}
+	if len(req.Password) < minPasswordLength {
+		return c.Status(400).JSON(fiber.Map{"error": "Password minimal 8 karakter"})
+	}
+
+	// NIM dan email harus cocok dengan data alumni yang sudah ada
+	alumni, err := s.alumni.GetByNIM(c.UserContext(), req.NIM)
+	if err != nil && err != sql.ErrNoRows {
+		return dbError(c, err)
+	}
+	if alumni == nil || !strings.EqualFold(alumni.Email, req.Email) {
+		return c.Status(400).JSON(fiber.Map{"error": "NIM dan email tidak cocok dengan data alumni"})
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return dbError(c, err)
+	}
+	user, err := s.users.Create(c.UserContext(), model.CreateUserRequest{
+		Username: req.Username,
+		Email:    alumni.Email,
+		Role:     "user",
+		AlumniID: &alumni.ID,
+	}, hash, false)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserConflict) {
+			return c.Status(409).JSON(fiber.Map{"error": "Username sudah dipakai atau alumni ini sudah punya akun"})
+		}
+		return dbError(c, err)
+	}
+
+	if err := s.sendVerification(user); err != nil {
+		// Akun tetap dibuat; user bisa minta kirim ulang lewat /register/resend
+		log.Printf("Gagal mengirim email verifikasi ke user %d: %v", user.ID, err)
+	}
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Registrasi berhasil, cek email Anda untuk verifikasi akun",
+	})
+}
+
+// POST /register/resend
+func (s *RegistrationService) ResendService(c *fiber.Ctx) error {
+	var req model.ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Email wajib diisi"})
+	}
+
+	user, err := s.users.GetByEmail(c.UserContext(), strings.TrimSpace(req.Email))
+	if err != nil && err != sql.ErrNoRows {
+		return dbError(c, err)
+	}
+	if user != nil && user.EmailVerifiedAt == nil {
+		if err := s.sendVerification(user); err != nil {
+			log.Printf("Gagal mengirim ulang email verifikasi ke user %d: %v", user.ID, err)
+		}
+	}
+	// Jawaban selalu sama supaya endpoint ini tidak bisa dipakai mengecek email terdaftar
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Kalau email terdaftar dan belum diverifikasi, link verifikasi sudah dikirim",
+	})
+}
+
+// GET /register/verify?token=
+func (s *RegistrationService) VerifyService(c *fiber.Ctx) error {
+	claims, err := utils.ValidateActionToken(purposeVerifyEmail, c.Query("token"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Link verifikasi tidak valid atau sudah kedaluwarsa"})
+	}
+
+	user, err := s.users.GetByID(c.UserContext(), claims.UserID)
+	if err != nil {
+		return userError(c, err)
+	}
+	// Link lama tidak berlaku lagi kalau email akun sudah diganti
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return c.Status(400).JSON(fiber.Map{"error": "Link verifikasi tidak valid atau sudah kedaluwarsa"})
+	}
+
+	if err := s.users.MarkEmailVerified(c.UserContext(), user.ID); err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Email terverifikasi, akun sudah aktif dan bisa dipakai login"})
+}
+
+func (s *RegistrationService) sendVerification(user *model.User) error {
+	token, err := utils.GenerateActionToken(purposeVerifyEmail, model.ActionClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+	}, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := s.cfg.App.BaseURL + s.cfg.App.BasePath + "/api/register/verify?token=" + url.QueryEscape(token)
+
+	return s.mailer.Send(utils.Mail{
+		To:      user.Email,
+		Subject: "Verifikasi akun Sistem Alumni",
+		Body: fmt.Sprintf("Halo %s,\n\n"+
+			"Klik link berikut untuk mengaktifkan akun Anda:\n%s\n\n"+
+			"Link berlaku %d jam. Abaikan email ini kalau Anda tidak merasa mendaftar.\n",
+			user.Username, link, int(emailVerificationTTL.Hours())),
+	})
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var verifyLinkPattern = regexp.MustCompile(`http://alumni\.test/api/register/verify\?token=(\S+)`)
+
+// registrationUsers -> user palsu di memori; hanya method yang dipakai
+// registrasi dan verifikasi email, method lain akan panic kalau terpanggil
+type registrationUsers struct {
+	repository.UserRepository
+	mu    sync.Mutex
+	users map[int]*model.User
+}
+
+func (f *registrationUsers) GetByID(ctx context.Context, id int) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copy := *u
+	return &copy, nil
+}
+
+func (f *registrationUsers) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, email) {
+			copy := *u
+			return &copy, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *registrationUsers) Create(ctx context.Context, req model.CreateUserRequest, passwordHash string, verified bool) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Username == req.Username || strings.EqualFold(u.Email, req.Email) {
+			return nil, repository.ErrUserConflict
+		}
+	}
+	u := &model.User{
+		ID:       len(f.users) + 1,
+		Username: req.Username,
+		Email:    req.Email,
+		Role:     req.Role,
+		AlumniID: req.AlumniID,
+		IsActive: true,
+	}
+	if verified {
+		now := time.Now()
+		u.EmailVerifiedAt = &now
+	}
+	f.users[u.ID] = u
+	copy := *u
+	return &copy, nil
+}
+
+func (f *registrationUsers) MarkEmailVerified(ctx context.Context, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if u.EmailVerifiedAt == nil {
+		now := time.Now()
+		u.EmailVerifiedAt = &now
+	}
+	return nil
+}
+
+type registrationAlumni struct {
+	repository.AlumniRepository
+	alumni []model.Alumni
+}
+
+func (f *registrationAlumni) GetByNIM(ctx context.Context, nim string) (*model.Alumni, error) {
+	for _, a := range f.alumni {
+		if a.NIM == nim {
+			copy := a
+			return &copy, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func newRegistrationApp(t *testing.T) (*fiber.App, *registrationUsers, string) {
+	t.Helper()
+	cfg := testConfig(t)
+	users := &registrationUsers{users: map[int]*model.User{}}
+	alumni := &registrationAlumni{alumni: []model.Alumni{
+		{ID: 7, NIM: "201101", Nama: "Budi Santoso", Email: "budi@alumni.example.ac.id"},
+	}}
+	svc := NewRegistrationService(users, alumni, utils.NewMailer(cfg.Mail), cfg)
+
+	app := fiber.New()
+	app.Post("/api/register", svc.RegisterService)
+	app.Post("/api/register/resend", svc.ResendService)
+	app.Get("/api/register/verify", svc.VerifyService)
+	return app, users, cfg.Mail.FileDir
+}
+
+// verifyToken -> ambil token dari link di email verifikasi
+func verifyToken(t *testing.T, mail string) string {
+	t.Helper()
+	match := verifyLinkPattern.FindStringSubmatch(mail)
+	if match == nil {
+		t.Fatalf("link verifikasi tidak ditemukan di email:\n%s", mail)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRegistrationEmailVerificationFlow(t *testing.T) {
+	app, users, mailDir := newRegistrationApp(t)
+
+	status, body := doJSON(t, app, "POST", "/api/register",
+		`{"nim":"201101","email":"BUDI@alumni.example.ac.id","username":"budi","password":"rahasia123"}`)
+	if status != 201 {
+		t.Fatalf("register: status %d, body %s", status, body)
+	}
+	user, _ := users.GetByID(nil, 1)
+	if user.EmailVerifiedAt != nil {
+		t.Fatal("akun baru seharusnya belum terverifikasi")
+	}
+	if user.AlumniID == nil || *user.AlumniID != 7 {
+		t.Errorf("akun seharusnya terhubung ke alumni 7, dapat %v", user.AlumniID)
+	}
+
+	mails := outbox(t, mailDir)
+	if len(mails) != 1 {
+		t.Fatalf("seharusnya 1 email verifikasi, ada %d", len(mails))
+	}
+	if !strings.Contains(mails[0], "To: budi@alumni.example.ac.id") {
+		t.Errorf("email dikirim ke alamat yang salah:\n%s", mails[0])
+	}
+	token := verifyToken(t, mails[0])
+
+	status, body = doJSON(t, app, "GET", "/api/register/verify?token="+url.QueryEscape(token+"x"), "")
+	if status != 400 {
+		t.Errorf("token rusak: status %d, seharusnya 400 (%s)", status, body)
+	}
+
+	status, body = doJSON(t, app, "GET", "/api/register/verify?token="+url.QueryEscape(token), "")
+	if status != 200 {
+		t.Fatalf("verify: status %d, body %s", status, body)
+	}
+	user, _ = users.GetByID(nil, 1)
+	if user.EmailVerifiedAt == nil {
+		t.Error("email seharusnya sudah terverifikasi")
+	}
+
+	// Akun yang sudah terverifikasi tidak dikirimi link lagi
+	status, _ = doJSON(t, app, "POST", "/api/register/resend", `{"email":"budi@alumni.example.ac.id"}`)
+	if status != 200 {
+		t.Errorf("resend: status %d", status)
+	}
+	if n := len(outbox(t, mailDir)); n != 1 {
+		t.Errorf("resend untuk akun terverifikasi seharusnya tidak mengirim email, jumlah email %d", n)
+	}
+}
+
+func TestRegistrationRejectsMismatchedAlumni(t *testing.T) {
+	app, _, mailDir := newRegistrationApp(t)
+
+	status, _ := doJSON(t, app, "POST", "/api/register",
+		`{"nim":"201101","email":"orang.lain@example.com","username":"budi","password":"rahasia123"}`)
+	if status != 400 {
+		t.Errorf("email tidak cocok: status %d, seharusnya 400", status)
+	}
+	if n := len(outbox(t, mailDir)); n != 0 {
+		t.Errorf("seharusnya tidak ada email terkirim, ada %d", n)
+	}
+}
+
+func TestVerificationLinkInvalidAfterEmailChange(t *testing.T) {
+	app, users, mailDir := newRegistrationApp(t)
+
+	if status, body := doJSON(t, app, "POST", "/api/register",
+		`{"nim":"201101","email":"budi@alumni.example.ac.id","username":"budi","password":"rahasia123"}`); status != 201 {
+		t.Fatalf("register: status %d, body %s", status, body)
+	}
+	token := verifyToken(t, outbox(t, mailDir)[0])
+
+	users.mu.Lock()
+	users.users[1].Email = "budi.baru@example.com"
+	users.mu.Unlock()
+
+	status, _ := doJSON(t, app, "GET", "/api/register/verify?token="+url.QueryEscape(token), "")
+	if status != 400 {
+		t.Errorf("link untuk email lama: status %d, seharusnya 400", status)
+	}
+}
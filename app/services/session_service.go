@@ -0,0 +1,67 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"tugas5/app/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SessionService struct {
+	sessions repository.SessionRepository
+}
+
+func NewSessionService(sessions repository.SessionRepository) *SessionService {
+	return &SessionService{sessions: sessions}
+}
+
+// GET /sessions
+func (s *SessionService) GetAllService(c *fiber.Ctx) error {
+	sessions, err := s.sessions.ListActiveForUser(c.UserContext(), c.Locals("user_id").(int))
+	if err != nil {
+		return dbError(c, err)
+	}
+	currentID, _ := c.Locals("session_id").(string)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return c.JSON(fiber.Map{"success": true, "data": sessions})
+}
+
+// DELETE /sessions/:id
+func (s *SessionService) RevokeService(c *fiber.Ctx) error {
+	id := c.Params("id")
+	// Sesi milik user lain dijawab sama dengan sesi yang tidak ada
+	if _, err := uuid.Parse(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Sesi tidak ditemukan"})
+	}
+	session, err := s.sessions.GetByID(c.UserContext(), id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return dbError(c, err)
+	}
+	if session == nil || session.UserID != c.Locals("user_id").(int) || session.RevokedAt != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Sesi tidak ditemukan"})
+	}
+
+	if err := s.sessions.Revoke(c.UserContext(), id); err != nil {
+		return dbError(c, err)
+	}
+	currentID, _ := c.Locals("session_id").(string)
+	return c.JSON(fiber.Map{"success": true, "message": "Sesi diakhiri", "current": id == currentID})
+}
+
+// GET /admin/users/:id/sessions
+func (s *SessionService) GetUserSessionsService(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	sessions, err := s.sessions.ListActiveForUser(c.UserContext(), userID)
+	if err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": sessions})
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/middleware"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	purposeMFALogin   = "mfa_login"
+	purposeMFAEnroll  = "mfa_enroll"
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var errInvalidMFAToken = errors.New("mfa_token tidak valid")
+
+type TwoFactorService struct {
+	auth      *AuthService
+	users     repository.UserRepository
+	twoFactor repository.TwoFactorRepository
+}
+
+func NewTwoFactorService(auth *AuthService, users repository.UserRepository, twoFactor repository.TwoFactorRepository) *TwoFactorService {
+	return &TwoFactorService{auth: auth, users: users, twoFactor: twoFactor}
+}
+
+// requireSecondFactor -> balas /login dengan mfa_token, bukan access token.
+// Akun yang wajib 2FA tapi belum mendaftar diarahkan ke pendaftaran dulu.
+func (s *AuthService) requireSecondFactor(c *fiber.Ctx, user model.User) error {
+	purpose := purposeMFALogin
+	if !user.TOTPEnabled {
+		purpose = purposeMFAEnroll
+	}
+	token, err := utils.GenerateActionToken(purpose, model.ActionClaims{UserID: user.ID}, mfaTokenTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Gagal membuat token",
+			"success": false,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(model.MFALoginResponse{
+		MFARequired:           user.TOTPEnabled,
+		MFAEnrollmentRequired: !user.TOTPEnabled,
+		MFAToken:              token,
+		ExpiresIn:             int64(mfaTokenTTL.Seconds()),
+	})
+}
+
+// POST /login/2fa
+func (s *TwoFactorService) LoginVerifyService(c *fiber.Ctx) error {
+	var req model.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "mfa_token dan code atau recovery_code diperlukan",
+			"success": false,
+		})
+	}
+
+	user, claims, err := s.userFromMFAToken(c.UserContext(), purposeMFALogin, req.MFAToken)
+	if err != nil {
+		return mfaTokenError(c, err)
+	}
+	if locked, err := s.checkThrottle(c, user.Username); locked || err != nil {
+		return err
+	}
+
+	var ok bool
+	if req.RecoveryCode != "" {
+		ok, err = s.twoFactor.UseRecoveryCode(c.UserContext(), user.ID, utils.HashToken(utils.NormalizeRecoveryCode(req.RecoveryCode)))
+		if ok {
+			log.Printf("User %s login memakai recovery code", user.Username)
+		}
+	} else {
+		ok, err = s.checkCode(c.UserContext(), user.ID, req.Code)
+	}
+	if err != nil {
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
+	}
+	if !ok {
+		s.recordFailure(c, user.Username)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Kode 2FA salah",
+			"success": false,
+		})
+	}
+	if err := s.consumeMFAToken(c.UserContext(), claims); err != nil {
+		return mfaTokenError(c, err)
+	}
+
+	return s.finishLogin(c, *user, nil)
+}
+
+// POST /login/2fa/enroll
+func (s *TwoFactorService) LoginEnrollService(c *fiber.Ctx) error {
+	var req model.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"success": false,
+		})
+	}
+	user, _, err := s.userFromMFAToken(c.UserContext(), purposeMFAEnroll, req.MFAToken)
+	if err != nil {
+		return mfaTokenError(c, err)
+	}
+	setup, err := s.newSecret(c.UserContext(), user)
+	if err != nil {
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal menyiapkan 2FA",
+			"success": false,
+		})
+	}
+	return c.JSON(fiber.Map{"success": true, "data": setup})
+}
+
+// POST /login/2fa/enroll/confirm
+func (s *TwoFactorService) LoginEnrollConfirmService(c *fiber.Ctx) error {
+	var req model.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "mfa_token dan code diperlukan",
+			"success": false,
+		})
+	}
+	user, claims, err := s.userFromMFAToken(c.UserContext(), purposeMFAEnroll, req.MFAToken)
+	if err != nil {
+		return mfaTokenError(c, err)
+	}
+	if locked, err := s.checkThrottle(c, user.Username); locked || err != nil {
+		return err
+	}
+
+	codes, ok, err := s.enable(c.UserContext(), user.ID, req.Code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Jalankan /login/2fa/enroll dulu atau 2FA sudah aktif",
+				"success": false,
+			})
+		}
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal mengaktifkan 2FA",
+			"success": false,
+		})
+	}
+	if !ok {
+		s.recordFailure(c, user.Username)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Kode 2FA salah",
+			"success": false,
+		})
+	}
+	if err := s.consumeMFAToken(c.UserContext(), claims); err != nil {
+		return mfaTokenError(c, err)
+	}
+	user.TOTPEnabled = true
+	return s.finishLogin(c, *user, codes)
+}
+
+// POST /2fa/setup
+func (s *TwoFactorService) SetupService(c *fiber.Ctx) error {
+	user, err := s.users.GetByID(c.UserContext(), c.Locals("user_id").(int))
+	if err != nil {
+		return userError(c, err)
+	}
+	if user.TOTPEnabled {
+		return c.Status(409).JSON(fiber.Map{"error": "2FA sudah aktif, matikan dulu untuk mengganti perangkat"})
+	}
+	setup, err := s.newSecret(c.UserContext(), user)
+	if err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": setup})
+}
+
+// POST /2fa/enable
+func (s *TwoFactorService) EnableService(c *fiber.Ctx) error {
+	var req model.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Kode 2FA wajib diisi"})
+	}
+	username := c.Locals("username").(string)
+	if locked, err := s.checkThrottle(c, username); locked || err != nil {
+		return err
+	}
+	codes, ok, err := s.enable(c.UserContext(), c.Locals("user_id").(int), req.Code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(409).JSON(fiber.Map{"error": "Jalankan /2fa/setup dulu atau 2FA sudah aktif"})
+		}
+		return dbError(c, err)
+	}
+	if !ok {
+		s.recordFailure(c, username)
+		return c.Status(400).JSON(fiber.Map{"error": "Kode 2FA salah"})
+	}
+	return c.JSON(fiber.Map{"success": true, "data": model.EnrollmentResponse{RecoveryCodes: codes}})
+}
+
+// POST /2fa/disable
+func (s *TwoFactorService) DisableService(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	if s.auth.cfg.Auth.MFARequired(c.Locals("role").(string)) {
+		return c.Status(403).JSON(fiber.Map{"error": "Role Anda wajib memakai 2FA"})
+	}
+
+	var req model.DisableTOTPRequest
+	if err := c.BodyParser(&req); err != nil || req.Password == "" || req.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Password dan kode 2FA wajib diisi"})
+	}
+	username := c.Locals("username").(string)
+	if locked, err := s.checkThrottle(c, username); locked || err != nil {
+		return err
+	}
+	hash, err := s.users.GetPasswordHash(c.UserContext(), userID)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !utils.CheckPassword(req.Password, hash) {
+		s.recordFailure(c, username)
+		return c.Status(400).JSON(fiber.Map{"error": "Password salah"})
+	}
+	ok, err := s.checkCode(c.UserContext(), userID, req.Code)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !ok {
+		s.recordFailure(c, username)
+		return c.Status(400).JSON(fiber.Map{"error": "Kode 2FA salah"})
+	}
+
+	if err := s.twoFactor.Disable(c.UserContext(), userID); err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "2FA dimatikan"})
+}
+
+// POST /2fa/recovery-codes
+func (s *TwoFactorService) RegenerateRecoveryCodesService(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req model.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Kode 2FA wajib diisi"})
+	}
+	username := c.Locals("username").(string)
+	if locked, err := s.checkThrottle(c, username); locked || err != nil {
+		return err
+	}
+	ok, err := s.checkCode(c.UserContext(), userID, req.Code)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !ok {
+		s.recordFailure(c, username)
+		return c.Status(400).JSON(fiber.Map{"error": "Kode 2FA salah"})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return dbError(c, err)
+	}
+	if err := s.twoFactor.ReplaceRecoveryCodes(c.UserContext(), userID, hashes); err != nil {
+		return dbError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": model.EnrollmentResponse{RecoveryCodes: codes}})
+}
+
+// DELETE /admin/users/:id/2fa
+func (s *TwoFactorService) AdminResetService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if _, err := s.users.GetByID(c.UserContext(), id); err != nil {
+		return userError(c, err)
+	}
+	if err := s.twoFactor.Disable(c.UserContext(), id); err != nil {
+		return dbError(c, err)
+	}
+	// Sesi lama dicabut supaya user login ulang dan (kalau wajib) mendaftar 2FA lagi
+	if err := s.auth.sessions.RevokeAllForUser(c.UserContext(), id); err != nil {
+		return dbError(c, err)
+	}
+	log.Printf("Admin %v mematikan 2FA user %d", c.Locals("username"), id)
+	return c.JSON(fiber.Map{"success": true, "message": "2FA user dimatikan"})
+}
+
+func (s *TwoFactorService) userFromMFAToken(ctx context.Context, purpose, token string) (*model.User, *model.ActionClaims, error) {
+	claims, err := utils.ValidateActionToken(purpose, token)
+	if err != nil || claims.ID == "" {
+		return nil, nil, errInvalidMFAToken
+	}
+	used, err := s.auth.sessions.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if used {
+		return nil, nil, errInvalidMFAToken
+	}
+	user, err := s.users.GetByID(ctx, claims.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, errInvalidMFAToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, repository.ErrAccountDisabled
+	}
+	return user, claims, nil
+}
+
+// consumeMFAToken -> jti mfa_token dicatat setelah langkah kedua berhasil,
+// sehingga token yang sama tidak bisa dipakai login lagi sampai kedaluwarsa
+func (s *TwoFactorService) consumeMFAToken(ctx context.Context, claims *model.ActionClaims) error {
+	consumed, err := s.auth.sessions.ConsumeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return errInvalidMFAToken
+	}
+	return nil
+}
+
+func mfaTokenError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, repository.ErrAccountDisabled) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Akun Anda dinonaktifkan, hubungi admin",
+			"success": false,
+		})
+	}
+	if errors.Is(err, errInvalidMFAToken) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "mfa_token tidak valid atau kedaluwarsa, silakan login ulang",
+			"success": false,
+		})
+	}
+	return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+		"message": "Gagal terhubung ke database",
+		"success": false,
+	})
+}
+
+// checkThrottle -> langkah kedua dan endpoint 2FA lain ikut kunci login yang
+// sama dengan password, supaya 10^6 kemungkinan kode tidak bisa ditebak
+// habis-habisan
+func (s *TwoFactorService) checkThrottle(c *fiber.Ctx, username string) (bool, error) {
+	lockedFor, err := s.auth.throttle.lockedFor(c.UserContext(), username, c.IP())
+	if err != nil {
+		return true, c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal terhubung ke database",
+			"success": false,
+		})
+	}
+	if lockedFor <= 0 {
+		return false, nil
+	}
+	retryAfter := int(math.Ceil(lockedFor.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return true, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"message":     "Terlalu banyak percobaan login gagal, coba lagi nanti",
+		"retry_after": retryAfter,
+		"success":     false,
+	})
+}
+
+// recordFailure -> kode atau password yang salah di endpoint 2FA dihitung
+// bersama login gagal
+func (s *TwoFactorService) recordFailure(c *fiber.Ctx, username string) {
+	if err := s.auth.throttle.recordFailure(c.UserContext(), username, c.IP()); err != nil {
+		log.Println("Gagal mencatat login gagal:", err)
+	}
+}
+
+// finishLogin -> langkah kedua berhasil, baru sekarang sesi dibuat
+func (s *TwoFactorService) finishLogin(c *fiber.Ctx, user model.User, recoveryCodes []string) error {
+	if err := s.auth.throttle.recordSuccess(c.UserContext(), user.Username); err != nil {
+		log.Println("Gagal mereset hitungan login gagal:", err)
+	}
+	response, err := s.auth.startSession(c, user)
+	if err != nil {
+		return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+			"message": "Gagal membuat token",
+			"success": false,
+		})
+	}
+	if recoveryCodes != nil {
+		return c.JSON(model.EnrollmentResponse{RecoveryCodes: recoveryCodes, Login: response})
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// newSecret -> secret baru disimpan sebagai "pending" sampai dikonfirmasi dengan kode pertama
+func (s *TwoFactorService) newSecret(ctx context.Context, user *model.User) (*model.TOTPSetupResponse, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.SetPendingSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+	return &model.TOTPSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: utils.TOTPProvisioningURI(secret, s.auth.cfg.JWT.Issuer, user.Username),
+	}, nil
+}
+
+// enable -> cek kode pertama terhadap secret pending, lalu aktifkan 2FA
+// bersama recovery code baru. ok=false kalau kodenya salah.
+func (s *TwoFactorService) enable(ctx context.Context, userID int, code string) ([]string, bool, error) {
+	state, err := s.twoFactor.GetState(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if state.Enabled || state.Secret == nil {
+		return nil, false, sql.ErrNoRows
+	}
+	step, ok := utils.VerifyTOTP(*state.Secret, strings.TrimSpace(code), time.Now(), state.LastStep)
+	if !ok {
+		return nil, false, nil
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.twoFactor.Enable(ctx, userID, step, hashes); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+// checkCode -> cocokkan kode TOTP lalu tandai langkahnya terpakai, sehingga
+// kode yang sama tidak bisa dipakai ulang dalam jendela 30 detiknya
+func (s *TwoFactorService) checkCode(ctx context.Context, userID int, code string) (bool, error) {
+	state, err := s.twoFactor.GetState(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !state.Enabled || state.Secret == nil {
+		return false, nil
+	}
+	step, ok := utils.VerifyTOTP(*state.Secret, strings.TrimSpace(code), time.Now(), state.LastStep)
+	if !ok {
+		return false, nil
+	}
+	return s.twoFactor.MarkStepUsed(ctx, userID, step)
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
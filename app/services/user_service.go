@@ -1,23 +1,49 @@
 package services
+
 import (
-"strconv"
-"strings"
-"github.com/gofiber/fiber/v2"
-"tugas5/app/model"
-"tugas5/app/repository"
+	"database/sql"
+	"errors"
+	"log"
+	"net/mail"
+	"strconv"
+	"strings"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
 )
 
-	// GetUsersService -> service untuk ambil data user dengan pagination, search, sorting
-func GetUsersService(c *fiber.Ctx) error {
+type UserService struct {
+	users       repository.UserRepository
+	alumni      repository.AlumniRepository
+	sessions    repository.SessionRepository
+	permissions repository.PermissionRepository
+}
+
+func NewUserService(users repository.UserRepository, alumni repository.AlumniRepository,
+	sessions repository.SessionRepository, permissions repository.PermissionRepository) *UserService {
+	return &UserService{users: users, alumni: alumni, sessions: sessions, permissions: permissions}
+}
+
+// GET /admin/users?page=&limit=&sortBy=&order=&search=&role=
+func (s *UserService) GetAllService(c *fiber.Ctx) error {
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	sortBy := c.Query("sortBy", "id")
 	order := c.Query("order", "asc")
 	search := c.Query("search", "")
+	role := c.Query("role", "")
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
 	offset := (page - 1) * limit
 
 	// Validasi input
-	sortByWhitelist := map[string]bool{"id": true, "name": true, "email": true, "created_at": true}
+	sortByWhitelist := map[string]bool{"id": true, "username": true, "email": true, "role": true, "created_at": true}
 	if !sortByWhitelist[sortBy] {
 		sortBy = "id"
 	}
@@ -25,37 +51,280 @@ func GetUsersService(c *fiber.Ctx) error {
 		order = "asc"
 	}
 
-// Ambil data dari repository
-	users, err := repository.GetUsersRepo(search, sortBy, order,
-limit, offset)
-if err != nil {
-return c.Status(500).JSON(fiber.Map{"error": "Failed to fetchusers"})
-}
-total, err := repository.CountUsersRepo(search)
-if err != nil {
-return c.Status(500).JSON(fiber.Map{"error": "Failed to countusers"})
-}
-
-// Buat response pakai model
-response := model.UserResponse{
-Data: users,
-Meta: model.MetaInfo{
-Page: page,
-Limit: limit,
-Total: total,
-Pages: (total + limit - 1) / limit,
-SortBy: sortBy,
-Order: order,
-Search: search,
-},
-}
-return c.JSON(response)
-}
-
-func GetProfileHandler(c *fiber.Ctx) error {
-	user := c.Locals("user").(model.User)
-	return c.JSON(fiber.Map{
-		"success": true,
-		"data":    user,
-	})
-}
\ No newline at end of file
+	users, err := s.users.List(c.UserContext(), search, role, sortBy, order, limit, offset)
+	if err != nil {
+		return dbError(c, err)
+	}
+	total, err := s.users.Count(c.UserContext(), search, role)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	response := model.UserResponse{
+		Data: users,
+		Meta: model.MetaInfo{
+			Page:   page,
+			Limit:  limit,
+			Total:  total,
+			Pages:  (total + limit - 1) / limit,
+			SortBy: sortBy,
+			Order:  order,
+			Search: search,
+		},
+	}
+	return c.JSON(response)
+}
+
+// GET /admin/users/:id
+func (s *UserService) GetByIDService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	user, err := s.users.GetByID(c.UserContext(), id)
+	if err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": user})
+}
+
+// POST /admin/users
+func (s *UserService) CreateService(c *fiber.Ctx) error {
+	var req model.CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Role == "" {
+		req.Role = "user"
+	}
+
+	if len(req.Username) < 3 {
+		return c.Status(400).JSON(fiber.Map{"error": "Username minimal 3 karakter"})
+	}
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Format email tidak valid"})
+	}
+	if len(req.Password) < minPasswordLength {
+		return c.Status(400).JSON(fiber.Map{"error": "Password minimal 8 karakter"})
+	}
+	exists, err := s.permissions.RoleExists(c.UserContext(), req.Role)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !exists {
+		return c.Status(400).JSON(fiber.Map{"error": "Role tidak dikenal: " + req.Role})
+	}
+	if req.AlumniID != nil {
+		if _, err := s.alumni.GetByID(c.UserContext(), *req.AlumniID); err != nil {
+			if err == sql.ErrNoRows {
+				return c.Status(404).JSON(fiber.Map{"error": "Alumni tidak ditemukan"})
+			}
+			return dbError(c, err)
+		}
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return dbError(c, err)
+	}
+	user, err := s.users.Create(c.UserContext(), req, hash, true)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserConflict) {
+			return c.Status(409).JSON(fiber.Map{"error": "Username, email, atau alumni sudah dipakai akun lain"})
+		}
+		return dbError(c, err)
+	}
+	return c.Status(201).JSON(fiber.Map{"success": true, "data": user})
+}
+
+// PUT /admin/users/:id/role
+func (s *UserService) UpdateRoleService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if isSelf(c, id) {
+		return c.Status(400).JSON(fiber.Map{"error": "Tidak dapat mengubah role atau status akun sendiri"})
+	}
+	var req model.UpdateRoleRequest
+	if err := c.BodyParser(&req); err != nil || req.Role == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "role wajib diisi"})
+	}
+	exists, err := s.permissions.RoleExists(c.UserContext(), req.Role)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !exists {
+		return c.Status(400).JSON(fiber.Map{"error": "Role tidak dikenal: " + req.Role})
+	}
+
+	if err := s.users.UpdateRole(c.UserContext(), id, req.Role); err != nil {
+		return userError(c, err)
+	}
+	// Role ikut tersimpan di token, jadi user harus login ulang untuk mendapat role baru
+	if err := s.sessions.RevokeAllForUser(c.UserContext(), id); err != nil {
+		return dbError(c, err)
+	}
+	log.Printf("Admin %v mengubah role user %d menjadi %s", c.Locals("username"), id, req.Role)
+	return s.respondUser(c, id)
+}
+
+// PUT /admin/users/:id/deactivate
+func (s *UserService) DeactivateService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if isSelf(c, id) {
+		return c.Status(400).JSON(fiber.Map{"error": "Tidak dapat mengubah role atau status akun sendiri"})
+	}
+	if err := s.users.SetActive(c.UserContext(), id, false); err != nil {
+		return userError(c, err)
+	}
+	if err := s.sessions.RevokeAllForUser(c.UserContext(), id); err != nil {
+		return dbError(c, err)
+	}
+	log.Printf("Admin %v menonaktifkan user %d", c.Locals("username"), id)
+	return s.respondUser(c, id)
+}
+
+// PUT /admin/users/:id/activate
+func (s *UserService) ActivateService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if err := s.users.SetActive(c.UserContext(), id, true); err != nil {
+		return userError(c, err)
+	}
+	log.Printf("Admin %v mengaktifkan kembali user %d", c.Locals("username"), id)
+	return s.respondUser(c, id)
+}
+
+// POST /admin/users/:id/reset-password
+func (s *UserService) ResetPasswordService(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	var req model.ResetPasswordRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Request body tidak valid"})
+		}
+	}
+
+	generated := req.Password == ""
+	if generated {
+		if req.Password, err = utils.GenerateTemporaryPassword(12); err != nil {
+			return dbError(c, err)
+		}
+	} else if len(req.Password) < minPasswordLength {
+		return c.Status(400).JSON(fiber.Map{"error": "Password minimal 8 karakter"})
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if err := s.users.ForcePasswordReset(c.UserContext(), id, hash); err != nil {
+		return userError(c, err)
+	}
+	if err := s.sessions.RevokeAllForUser(c.UserContext(), id); err != nil {
+		return dbError(c, err)
+	}
+	log.Printf("Admin %v mereset password user %d", c.Locals("username"), id)
+
+	response := fiber.Map{"success": true, "message": "Password direset, user wajib menggantinya setelah login"}
+	if generated {
+		// Hanya ditampilkan sekali di sini, tidak disimpan dalam bentuk asli
+		response["temporary_password"] = req.Password
+	}
+	return c.JSON(response)
+}
+
+// PUT /admin/users/:id/alumni
+func (s *UserService) LinkAlumniService(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	var req model.LinkAlumniRequest
+	if err := c.BodyParser(&req); err != nil || req.AlumniID <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "alumni_id wajib diisi"})
+	}
+
+	if _, err := s.alumni.GetByID(c.UserContext(), req.AlumniID); err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": "Alumni tidak ditemukan"})
+		}
+		return dbError(c, err)
+	}
+
+	if err := s.users.LinkAlumni(c.UserContext(), userID, req.AlumniID); err != nil {
+		if errors.Is(err, repository.ErrAlumniAlreadyLinked) {
+			return c.Status(409).JSON(fiber.Map{"error": "Alumni sudah terhubung dengan akun lain"})
+		}
+		return userError(c, err)
+	}
+	return s.respondUser(c, userID)
+}
+
+// DELETE /admin/users/:id/alumni
+func (s *UserService) UnlinkAlumniService(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if err := s.users.UnlinkAlumni(c.UserContext(), userID); err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Link alumni dilepas"})
+}
+
+// PUT /admin/users/:id/ldap
+func (s *UserService) LinkLDAPService(c *fiber.Ctx) error {
+	return s.setLDAPLinked(c, true)
+}
+
+// DELETE /admin/users/:id/ldap
+func (s *UserService) UnlinkLDAPService(c *fiber.Ctx) error {
+	return s.setLDAPLinked(c, false)
+}
+
+// setLDAPLinked -> akun yang dihubungkan bisa login dengan username dan password
+// direktori, dan role-nya ikut disinkronkan dari grup LDAP
+func (s *UserService) setLDAPLinked(c *fiber.Ctx, linked bool) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+	if err := s.users.SetLDAPLinked(c.UserContext(), userID, linked); err != nil {
+		return userError(c, err)
+	}
+	log.Printf("Admin %v mengubah link LDAP user %d menjadi %v", c.Locals("username"), userID, linked)
+	return s.respondUser(c, userID)
+}
+
+// isSelf -> admin tidak boleh menurunkan atau menonaktifkan akunnya sendiri
+// supaya tidak terkunci dari sistem
+func isSelf(c *fiber.Ctx, id int) bool {
+	return id == c.Locals("user_id").(int)
+}
+
+func (s *UserService) respondUser(c *fiber.Ctx, id int) error {
+	user, err := s.users.GetByID(c.UserContext(), id)
+	if err != nil {
+		return userError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": user})
+}
+
+func userError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	return dbError(c, err)
+}
@@ -0,0 +1,126 @@
+// Command migrate -> kelola skema database dari file di database/migrations.
+//
+//	go run ./cmd/migrate up [n]          terapkan migrasi yang belum jalan (atau n berikutnya)
+//	go run ./cmd/migrate down [n]        rollback n migrasi terakhir (default 1)
+//	go run ./cmd/migrate status          daftar migrasi dan kapan diterapkan
+//	go run ./cmd/migrate create <nama>   buat file up/down baru
+//	go run ./cmd/migrate baseline <versi> tandai migrasi sampai versi ini sudah jalan
+//
+// baseline dipakai sekali untuk database lama yang skemanya dibuat manual,
+// supaya migrasi yang sudah diterapkan dengan tangan tidak dijalankan ulang.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"tugas5/config"
+	"tugas5/database"
+)
+
+func main() {
+	dir := flag.String("dir", "database/migrations", "folder file migrasi (untuk create)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [-dir folder] up [n] | down [n] | status | create <nama> | baseline <versi>")
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// create tidak butuh koneksi database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		up, down, err := database.CreateMigration(*dir, args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("dibuat:", up)
+		fmt.Println("dibuat:", down)
+		return
+	}
+
+	cfg, err := config.LoadEnv()
+	if err != nil {
+		log.Fatal("Konfigurasi tidak valid: ", err)
+	}
+	database.ConnectDB(cfg.Database)
+	defer database.DB.Close()
+
+	migrator, err := database.NewMigrator(database.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(countArg(args, 0))
+		printMigrations("diterapkan", applied)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("skema sudah terbaru")
+		}
+	case "down":
+		reverted, err := migrator.Down(countArg(args, 1))
+		printMigrations("di-rollback", reverted)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			applied := "belum diterapkan"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-28s %s\n", s.Version, s.Name, applied)
+		}
+	case "baseline":
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatalf("versi tidak valid: %q", args[1])
+		}
+		marked, err := migrator.Baseline(version)
+		printMigrations("ditandai", marked)
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// countArg -> argumen jumlah opsional setelah perintah
+func countArg(args []string, defaultValue int) int {
+	if len(args) < 2 {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 {
+		log.Fatalf("jumlah migrasi tidak valid: %q", args[1])
+	}
+	return n
+}
+
+func printMigrations(verb string, migrations []database.Migration) {
+	for _, m := range migrations {
+		fmt.Printf("%s: %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
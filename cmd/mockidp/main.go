@@ -0,0 +1,42 @@
+// Command mockidp -> identity provider OIDC palsu untuk menguji login SSO di lokal.
+// Setiap permintaan /authorize langsung disetujui sebagai user yang diatur lewat
+// flag (atau ?login_hint=<email>), jadi tidak ada halaman login.
+//
+//	go run ./cmd/mockidp -addr :9000 -email dosen@kampus.ac.id
+//
+// lalu isi OIDC_ISSUER_URL=http://localhost:9000 dan OIDC_CLIENT_ID=alumni-api.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"tugas5/cmd/mockidp/idp"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "alamat listen")
+	issuer := flag.String("issuer", "", "issuer URL (default http://localhost<addr>)")
+	clientID := flag.String("client-id", "alumni-api", "client_id yang diterima")
+	email := flag.String("email", "admin@localhost", "email user yang login")
+	username := flag.String("username", "", "preferred_username (default bagian depan email)")
+	emailVerified := flag.Bool("email-verified", true, "nilai claim email_verified")
+	flag.Parse()
+
+	if *issuer == "" {
+		*issuer = "http://localhost" + *addr
+	}
+	server, err := idp.New(idp.Config{
+		Issuer:        *issuer,
+		ClientID:      *clientID,
+		Email:         *email,
+		Username:      *username,
+		EmailVerified: *emailVerified,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Mock IdP jalan di %s (issuer %s, user %s)", *addr, *issuer, *email)
+	log.Fatal(http.ListenAndServe(*addr, server.Handler()))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"tugas5/app/model"
+)
+
+// seedReference -> "hari ini" versi seed; tanggal tidak boleh ikut jam sistem
+// supaya hasilnya sama persis setiap kali dijalankan
+var seedReference = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var firstNames = []string{
+	"Adi", "Agus", "Ahmad", "Andi", "Anisa", "Ayu", "Bagus", "Budi", "Citra", "Dewi",
+	"Dimas", "Eka", "Fajar", "Fitri", "Galih", "Hendra", "Indah", "Intan", "Joko", "Kartika",
+	"Lestari", "Maya", "Nanda", "Nur", "Putri", "Rani", "Rizky", "Sari", "Teguh", "Wulan",
+}
+
+var lastNames = []string{
+	"Pratama", "Saputra", "Wijaya", "Hidayat", "Nugroho", "Santoso", "Lestari", "Kurniawan",
+	"Setiawan", "Permana", "Utami", "Rahmawati", "Susanto", "Firmansyah", "Wibowo", "Handayani",
+}
+
+var cities = []string{
+	"Surabaya", "Jakarta", "Bandung", "Yogyakarta", "Semarang", "Malang", "Denpasar", "Makassar", "Medan", "Sidoarjo",
+}
+
+type jurusan struct {
+	nama string
+	kode string // dipakai di NIM
+}
+
+var jurusanList = []jurusan{
+	{"Teknik Informatika", "11"},
+	{"Sistem Informasi", "12"},
+	{"Teknik Elektro", "21"},
+	{"Manajemen", "31"},
+	{"Akuntansi", "32"},
+	{"Ilmu Komunikasi", "41"},
+}
+
+type industry struct {
+	bidang    string
+	companies []string
+	positions []string
+}
+
+var industries = []industry{
+	{"Teknologi Informasi", []string{"PT Nusantara Digital", "PT Awan Data Indonesia", "PT Kode Kreatif"},
+		[]string{"Software Engineer", "Backend Developer", "Data Analyst", "QA Engineer"}},
+	{"Perbankan", []string{"Bank Sejahtera", "Bank Mitra Usaha", "Bank Amanah Syariah"},
+		[]string{"Analis Kredit", "Staf IT", "Relationship Manager"}},
+	{"Telekomunikasi", []string{"PT Sinyal Nusantara", "PT Jaringan Prima"},
+		[]string{"Network Engineer", "Product Manager", "Teknisi Lapangan"}},
+	{"Manufaktur", []string{"PT Baja Timur", "PT Sinar Tekstil", "PT Pangan Lestari"},
+		[]string{"Staf Produksi", "Supervisor Gudang", "Staf Keuangan"}},
+	{"Media", []string{"PT Kabar Kota", "Radio Suara Warga"},
+		[]string{"Jurnalis", "Editor", "Social Media Specialist"}},
+	{"Pendidikan", []string{"Universitas Harapan Bangsa", "SMA Negeri 5"},
+		[]string{"Dosen", "Guru", "Staf Administrasi"}},
+}
+
+var gajiRanges = []string{"3-5 juta", "5-8 juta", "8-12 juta", "12-20 juta", "> 20 juta"}
+
+type seedJob struct {
+	req     model.CreatePekerjaanRequest
+	deleted bool
+}
+
+type seedAlumni struct {
+	req  model.CreateAlumniRequest
+	jobs []seedJob
+}
+
+// generateAlumni -> n alumni dengan riwayat pekerjaan; seed yang sama selalu
+// menghasilkan data yang sama
+func generateAlumni(seed int64, n int) []seedAlumni {
+	rng := rand.New(rand.NewSource(seed))
+	counter := map[string]int{} // nomor urut per jurusan+angkatan untuk NIM
+
+	alumni := make([]seedAlumni, 0, n)
+	for i := 0; i < n; i++ {
+		first := firstNames[rng.Intn(len(firstNames))]
+		last := lastNames[rng.Intn(len(lastNames))]
+		j := jurusanList[rng.Intn(len(jurusanList))]
+		angkatan := 2012 + rng.Intn(9)
+		tahunLulus := angkatan + 4 + rng.Intn(2)
+
+		key := j.kode + fmt.Sprint(angkatan)
+		counter[key]++
+		nim := fmt.Sprintf("%02d%s%04d", angkatan%100, j.kode, counter[key])
+
+		telepon := fmt.Sprintf("08%d%09d", 1+rng.Intn(9), rng.Intn(1_000_000_000))
+		alamat := fmt.Sprintf("Jl. %s No. %d, %s", lastNames[rng.Intn(len(lastNames))], 1+rng.Intn(150), cities[rng.Intn(len(cities))])
+
+		a := seedAlumni{req: model.CreateAlumniRequest{
+			NIM:        nim,
+			Nama:       first + " " + last,
+			Jurusan:    j.nama,
+			Angkatan:   angkatan,
+			TahunLulus: tahunLulus,
+			Email:      fmt.Sprintf("%s.%s.%s@alumni.example.ac.id", strings.ToLower(first), strings.ToLower(last), nim),
+			NoTelepon:  &telepon,
+			Alamat:     &alamat,
+		}}
+		a.jobs = generateJobs(rng, tahunLulus)
+		alumni = append(alumni, a)
+	}
+	return alumni
+}
+
+// generateJobs -> 0-3 pekerjaan berurutan sejak lulus; pekerjaan terakhir bisa
+// masih aktif, dan sebagian kecil sudah di-soft delete
+func generateJobs(rng *rand.Rand, tahunLulus int) []seedJob {
+	count := rng.Intn(4)
+	start := time.Date(tahunLulus, time.Month(1+rng.Intn(12)), 1, 0, 0, 0, 0, time.UTC).AddDate(0, rng.Intn(6), 0)
+
+	var jobs []seedJob
+	for k := 0; k < count && start.Before(seedReference); k++ {
+		ind := industries[rng.Intn(len(industries))]
+		gaji := gajiRanges[min(k+rng.Intn(3), len(gajiRanges)-1)]
+		deskripsi := "Data contoh hasil seed"
+
+		req := model.CreatePekerjaanRequest{
+			NamaPerusahaan:     ind.companies[rng.Intn(len(ind.companies))],
+			PosisiJabatan:      ind.positions[rng.Intn(len(ind.positions))],
+			BidangIndustri:     ind.bidang,
+			LokasiKerja:        cities[rng.Intn(len(cities))],
+			GajiRange:          &gaji,
+			TanggalMulaiKerja:  start.Format("2006-01-02"),
+			DeskripsiPekerjaan: &deskripsi,
+		}
+
+		end := start.AddDate(1+rng.Intn(3), rng.Intn(12), 0)
+		status := "aktif"
+		if k < count-1 || rng.Intn(4) == 0 {
+			if end.After(seedReference) {
+				end = seedReference
+			}
+			selesai := end.Format("2006-01-02")
+			req.TanggalSelesaiKerja = &selesai
+			status = "selesai"
+		}
+		req.StatusPekerjaan = &status
+
+		jobs = append(jobs, seedJob{req: req, deleted: rng.Intn(10) == 0})
+		start = end.AddDate(0, 1+rng.Intn(3), 0)
+	}
+	return jobs
+}
@@ -0,0 +1,121 @@
+// Command seed -> isi database dev/demo dengan data alumni, pekerjaan dan akun
+// contoh. Hasilnya deterministik: seed dan jumlah yang sama selalu menghasilkan
+// data yang sama (kecuali hash bcrypt dan timestamp created_at).
+//
+//	go run ./cmd/seed -reset -alumni 100 -users 10
+//
+// Akun yang dibuat: admin, operator, dan alumni1..alumniN yang terhubung ke
+// alumni pertama; semuanya memakai password dari -password.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/config"
+	"tugas5/database"
+	"tugas5/utils"
+)
+
+func main() {
+	seed := flag.Int64("seed", 1, "seed generator data")
+	alumniCount := flag.Int("alumni", 50, "jumlah alumni")
+	userCount := flag.Int("users", 10, "jumlah akun alumni (terhubung ke alumni pertama)")
+	password := flag.String("password", "password123", "password semua akun contoh")
+	reset := flag.Bool("reset", false, "kosongkan users, alumni, pekerjaan dan revisi sebelum seed")
+	force := flag.Bool("force", false, "izinkan seed walaupun APP_ENV=production")
+	flag.Parse()
+
+	if *userCount > *alumniCount {
+		log.Fatal("-users tidak boleh lebih dari -alumni")
+	}
+
+	cfg, err := config.LoadEnv()
+	if err != nil {
+		log.Fatal("Konfigurasi tidak valid: ", err)
+	}
+	if cfg.App.Env == "production" && !*force {
+		log.Fatal("Menolak seed di production; pakai -force kalau memang disengaja")
+	}
+	database.ConnectDB(cfg.Database)
+	defer database.DB.Close()
+
+	ctx := context.Background()
+	if *reset {
+		if err := resetData(ctx, database.DB); err != nil {
+			log.Fatal("Gagal mengosongkan data: ", err)
+		}
+	}
+
+	hash, err := utils.HashPassword(*password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	alumniRepo := repository.NewAlumniRepository(database.DB)
+	pekerjaanRepo := repository.NewPekerjaanRepository(database.DB)
+	userRepo := repository.NewUserRepository(database.DB)
+
+	for _, u := range []model.CreateUserRequest{
+		{Username: "admin", Email: "admin@example.ac.id", Role: "admin"},
+		{Username: "operator", Email: "operator@example.ac.id", Role: "operator_prodi"},
+	} {
+		if _, err := userRepo.Create(ctx, u, hash, true); err != nil {
+			log.Fatalf("Gagal membuat user %s: %v (database sudah berisi data? pakai -reset)", u.Username, err)
+		}
+	}
+
+	var jobs, deleted int
+	for i, a := range generateAlumni(*seed, *alumniCount) {
+		alumni, err := alumniRepo.Create(ctx, a.req)
+		if err != nil {
+			log.Fatalf("Gagal membuat alumni %s: %v (database sudah berisi data? pakai -reset)", a.req.NIM, err)
+		}
+
+		// Pekerjaan dibuat atas nama akun alumni kalau ada, selain itu oleh operator
+		createdBy := "operator"
+		if i < *userCount {
+			username := fmt.Sprintf("alumni%d", i+1)
+			if _, err := userRepo.Create(ctx, model.CreateUserRequest{
+				Username: username,
+				Email:    alumni.Email,
+				Role:     "user",
+				AlumniID: &alumni.ID,
+			}, hash, true); err != nil {
+				log.Fatalf("Gagal membuat user %s: %v", username, err)
+			}
+			createdBy = username
+		}
+
+		for _, job := range a.jobs {
+			job.req.AlumniID = alumni.ID
+			job.req.CreatedBy = utils.StringPtr(createdBy)
+			p, err := pekerjaanRepo.Create(ctx, job.req)
+			if err != nil {
+				log.Fatalf("Gagal membuat pekerjaan alumni %s: %v", alumni.NIM, err)
+			}
+			jobs++
+			if job.deleted {
+				if err := pekerjaanRepo.Delete(ctx, p.ID); err != nil {
+					log.Fatal(err)
+				}
+				deleted++
+			}
+		}
+	}
+
+	log.Printf("Seed %d selesai: %d alumni, %d pekerjaan (%d di trash), %d akun alumni + admin dan operator",
+		*seed, *alumniCount, jobs, deleted, *userCount)
+}
+
+// resetData -> RESTART IDENTITY supaya ID hasil seed juga sama setiap kali.
+// CASCADE ikut mengosongkan tabel yang merujuk users (sesi, API key, dll).
+// audit_log tidak disentuh karena memang append-only.
+func resetData(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `TRUNCATE pekerjaan, alumni, users, revisions RESTART IDENTITY CASCADE`)
+	return err
+}
@@ -1,17 +1,276 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
-func LoadEnv() {
+// Config -> seluruh pengaturan aplikasi, diisi sekali saat startup
+type Config struct {
+	App      AppConfig
+	Database DatabaseConfig
+	JWT      JWTConfig
+	Mail     MailConfig
+	Auth     AuthConfig
+	OIDC     OIDCConfig
+	LDAP     LDAPConfig
+}
+
+type AppConfig struct {
+	Env      string // development, staging, production
+	Port     string
+	BasePath string // prefix semua route, kosong = di root
+	BaseURL  string // URL publik server, dipakai untuk link di email
+}
+
+type DatabaseConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+
+	// AutoMigrate -> jalankan migrasi yang belum diterapkan saat server start
+	AutoMigrate bool
+
+	// ReadTimeout/WriteTimeout -> batas waktu satu query baca dan satu operasi
+	// tulis (termasuk transaksi); lewat dari itu request dijawab 504
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// DSN -> connection string untuk driver lib/pq
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+}
+
+type MailConfig struct {
+	Driver       string // file atau smtp
+	From         string
+	FileDir      string // driver file: tiap email ditulis sebagai .eml di sini
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+}
+
+// AuthConfig -> kebijakan login dan pemulihan akun
+type AuthConfig struct {
+	PasswordResetTTL time.Duration
+
+	// Brute-force protection untuk /api/login
+	MaxFailuresPerUser int           // gagal berturut-turut sebelum akun dikunci
+	MaxFailuresPerIP   int           // gagal dari satu alamat sebelum alamat dikunci
+	FailureWindow      time.Duration // hitungan gagal direset kalau tidak ada kegagalan selama ini
+	LockoutBase        time.Duration // lama kunci pertama, berlipat dua tiap kegagalan berikutnya
+	LockoutMax         time.Duration
+
+	// UserCacheTTL -> status dan role user di middleware boleh basi paling lama
+	// selama ini kalau diubah dari instance server lain
+	UserCacheTTL time.Duration
+
+	// ImpersonationTTL -> umur token impersonasi admin, tidak bisa diperpanjang
+	ImpersonationTTL time.Duration
+
+	// MFARequiredRoles -> role yang wajib memakai TOTP; login tanpa 2FA diarahkan ke pendaftaran
+	MFARequiredRoles []string
+}
+
+// MFARequired -> apakah role ini wajib 2FA
+func (a AuthConfig) MFARequired(role string) bool {
+	for _, r := range a.MFARequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCConfig -> login SSO lewat identity provider kampus; nonaktif kalau IssuerURL kosong
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // harus sama persis dengan yang didaftarkan di IdP
+	Scopes       []string // selain openid
+	AutoCreate   bool     // buat akun baru untuk subject yang belum dikenal
+	DefaultRole  string   // role akun yang dibuat otomatis
+}
+
+// Enabled -> apakah login SSO dipasang
+func (o OIDCConfig) Enabled() bool {
+	return o.IssuerURL != ""
+}
+
+// LDAPConfig -> login lewat direktori kampus (LDAP/Active Directory); nonaktif kalau URL kosong
+type LDAPConfig struct {
+	URL          string // ldap://host:389 atau ldaps://host:636
+	StartTLS     bool
+	BindDN       string // akun layanan untuk mencari DN user; kosong = bind anonim
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // %s diganti username yang sudah di-escape
+	EmailAttr    string
+	GroupAttr    string          // atribut grup di entri user, mis. memberOf
+	GroupRoles   []LDAPGroupRole // dicek berurutan, grup pertama yang cocok menentukan role
+	DefaultRole  string          // role kalau tidak ada grup yang cocok; kosong = login ditolak
+	Timeout      time.Duration
+}
+
+// LDAPGroupRole -> anggota grup GroupDN mendapat role Role
+type LDAPGroupRole struct {
+	GroupDN string
+	Role    string
+}
+
+// Enabled -> apakah login LDAP dipasang
+func (l LDAPConfig) Enabled() bool {
+	return l.URL != ""
+}
+
+type JWTConfig struct {
+	Issuer      string
+	Algorithm   string // RS256 atau EdDSA, dipakai saat membuat kunci baru
+	KeyDir      string
+	KeyRotation time.Duration // umur kunci aktif sebelum diganti
+	KeyGrace    time.Duration // kunci lama masih dipakai verifikasi selama ini
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+}
+
+// requiredKeys -> key yang wajib ada, tidak punya nilai default
+var requiredKeys = []string{"DB_HOST", "DB_USER", "DB_NAME"}
+
+// LoadEnv -> baca .env (kalau ada) lalu environment, dan validasi hasilnya
+func LoadEnv() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("Warning: .env file not found, using system environment variables")
 	}
+
+	var missing []string
+	for _, key := range requiredKeys {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("konfigurasi wajib belum diisi: %s", strings.Join(missing, ", "))
+	}
+
+	p := &envParser{}
+	port := GetEnv("APP_PORT", "3000")
+	cfg := &Config{
+		App: AppConfig{
+			Env:      GetEnv("APP_ENV", "development"),
+			Port:     port,
+			BasePath: strings.TrimSuffix(os.Getenv("BASE_PATH"), "/"),
+			BaseURL:  strings.TrimSuffix(GetEnv("APP_BASE_URL", "http://localhost:"+port), "/"),
+		},
+		Database: DatabaseConfig{
+			Host:     os.Getenv("DB_HOST"),
+			Port:     GetEnv("DB_PORT", "5432"),
+			User:     os.Getenv("DB_USER"),
+			Password: os.Getenv("DB_PASSWORD"),
+			Name:     os.Getenv("DB_NAME"),
+			SSLMode:  GetEnv("DB_SSLMODE", "disable"),
+
+			AutoMigrate:  p.boolean("DB_AUTO_MIGRATE", false),
+			ReadTimeout:  p.duration("DB_READ_TIMEOUT", 5*time.Second),
+			WriteTimeout: p.duration("DB_WRITE_TIMEOUT", 10*time.Second),
+		},
+		JWT: JWTConfig{
+			Issuer:      GetEnv("JWT_ISSUER", "alumni-api"),
+			Algorithm:   GetEnv("JWT_ALGORITHM", "RS256"),
+			KeyDir:      GetEnv("JWT_KEY_DIR", "keys"),
+			KeyRotation: p.duration("JWT_KEY_ROTATION", 30*24*time.Hour),
+			KeyGrace:    p.duration("JWT_KEY_GRACE", 48*time.Hour),
+			AccessTTL:   p.duration("JWT_ACCESS_TTL", 15*time.Minute),
+			RefreshTTL:  p.duration("JWT_REFRESH_TTL", 7*24*time.Hour),
+		},
+		Mail: MailConfig{
+			Driver:       GetEnv("MAIL_DRIVER", "file"),
+			From:         GetEnv("MAIL_FROM", "Sistem Alumni <no-reply@localhost>"),
+			FileDir:      GetEnv("MAIL_FILE_DIR", "mail_outbox"),
+			SMTPHost:     os.Getenv("SMTP_HOST"),
+			SMTPPort:     GetEnv("SMTP_PORT", "1025"),
+			SMTPUser:     os.Getenv("SMTP_USER"),
+			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		},
+		Auth: AuthConfig{
+			PasswordResetTTL:   p.duration("PASSWORD_RESET_TTL", time.Hour),
+			MaxFailuresPerUser: p.integer("LOGIN_MAX_FAILURES_PER_USER", 5),
+			MaxFailuresPerIP:   p.integer("LOGIN_MAX_FAILURES_PER_IP", 20),
+			FailureWindow:      p.duration("LOGIN_FAILURE_WINDOW", time.Hour),
+			LockoutBase:        p.duration("LOGIN_LOCKOUT_BASE", time.Minute),
+			LockoutMax:         p.duration("LOGIN_LOCKOUT_MAX", time.Hour),
+			UserCacheTTL:       p.duration("AUTH_USER_CACHE_TTL", 30*time.Second),
+			ImpersonationTTL:   p.duration("IMPERSONATION_TTL", 15*time.Minute),
+			MFARequiredRoles:   mfaRequiredRoles(),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:    strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       list(GetEnv("OIDC_SCOPES", "profile,email")),
+			AutoCreate:   p.boolean("OIDC_AUTO_CREATE", false),
+			DefaultRole:  GetEnv("OIDC_DEFAULT_ROLE", "user"),
+		},
+		LDAP: LDAPConfig{
+			URL:          os.Getenv("LDAP_URL"),
+			StartTLS:     p.boolean("LDAP_STARTTLS", false),
+			BindDN:       os.Getenv("LDAP_BIND_DN"),
+			BindPassword: os.Getenv("LDAP_BIND_PASSWORD"),
+			BaseDN:       os.Getenv("LDAP_BASE_DN"),
+			UserFilter:   GetEnv("LDAP_USER_FILTER", "(&(objectClass=person)(uid=%s))"),
+			EmailAttr:    GetEnv("LDAP_EMAIL_ATTR", "mail"),
+			GroupAttr:    GetEnv("LDAP_GROUP_ATTR", "memberOf"),
+			GroupRoles:   p.groupRoles("LDAP_GROUP_ROLES"),
+			DefaultRole:  os.Getenv("LDAP_DEFAULT_ROLE"),
+			Timeout:      p.duration("LDAP_TIMEOUT", 5*time.Second),
+		},
+	}
+	if cfg.OIDC.RedirectURL == "" {
+		cfg.OIDC.RedirectURL = cfg.App.BaseURL + cfg.App.BasePath + "/api/oidc/callback"
+	}
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+
+	if cfg.App.BasePath != "" && !strings.HasPrefix(cfg.App.BasePath, "/") {
+		return nil, fmt.Errorf("BASE_PATH harus diawali '/': %q", cfg.App.BasePath)
+	}
+	if cfg.JWT.Algorithm != "RS256" && cfg.JWT.Algorithm != "EdDSA" {
+		return nil, fmt.Errorf("JWT_ALGORITHM harus RS256 atau EdDSA: %q", cfg.JWT.Algorithm)
+	}
+	switch cfg.Mail.Driver {
+	case "file":
+	case "smtp":
+		if cfg.Mail.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST wajib diisi kalau MAIL_DRIVER=smtp")
+		}
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER harus file atau smtp: %q", cfg.Mail.Driver)
+	}
+	if cfg.OIDC.Enabled() && cfg.OIDC.ClientID == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID wajib diisi kalau OIDC_ISSUER_URL diisi")
+	}
+	if cfg.LDAP.Enabled() && (cfg.LDAP.BaseDN == "" || !strings.Contains(cfg.LDAP.UserFilter, "%s")) {
+		return nil, fmt.Errorf("LDAP_BASE_DN wajib diisi dan LDAP_USER_FILTER harus memuat %%s kalau LDAP_URL diisi")
+	}
+	if cfg.JWT.KeyGrace < cfg.JWT.AccessTTL {
+		return nil, fmt.Errorf("JWT_KEY_GRACE tidak boleh lebih pendek dari JWT_ACCESS_TTL")
+	}
+	return cfg, nil
 }
 
 func GetEnv(key, defaultValue string) string {
@@ -21,3 +280,92 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// mfaRequiredRoles -> default admin; MFA_REQUIRED_ROLES= (kosong) mematikan kewajiban 2FA
+func mfaRequiredRoles() []string {
+	value, ok := os.LookupEnv("MFA_REQUIRED_ROLES")
+	if !ok {
+		value = "admin"
+	}
+	return list(value)
+}
+
+// list -> nilai dipisah koma, entri kosong dibuang
+func list(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envParser -> kumpulkan semua nilai yang salah format supaya dilaporkan sekaligus
+type envParser struct {
+	invalid []string
+}
+
+func (p *envParser) duration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		p.invalid = append(p.invalid, fmt.Sprintf("%s=%q (contoh: 15m, 24h)", key, value))
+		return defaultValue
+	}
+	return d
+}
+
+func (p *envParser) integer(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		p.invalid = append(p.invalid, fmt.Sprintf("%s=%q (harus bilangan bulat positif)", key, value))
+		return defaultValue
+	}
+	return n
+}
+
+func (p *envParser) boolean(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.invalid = append(p.invalid, fmt.Sprintf("%s=%q (harus true atau false)", key, value))
+		return defaultValue
+	}
+	return b
+}
+
+// groupRoles -> format "<dn grup>=>role;<dn grup>=>role", urutan = prioritas
+func (p *envParser) groupRoles(key string) []LDAPGroupRole {
+	var mappings []LDAPGroupRole
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		dn, role, ok := strings.Cut(entry, "=>")
+		dn, role = strings.TrimSpace(dn), strings.TrimSpace(role)
+		if !ok || dn == "" || role == "" {
+			p.invalid = append(p.invalid, fmt.Sprintf("%s=%q (contoh: cn=admin,ou=groups,dc=kampus,dc=ac,dc=id=>admin)", key, entry))
+			continue
+		}
+		mappings = append(mappings, LDAPGroupRole{GroupDN: dn, Role: role})
+	}
+	return mappings
+}
+
+func (p *envParser) err() error {
+	if len(p.invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("format konfigurasi tidak valid: %s", strings.Join(p.invalid, ", "))
+}
@@ -4,18 +4,17 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"tugas5/config"
 
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-func ConnectDB() {
+func ConnectDB(cfg config.DatabaseConfig) {
 	var err error
 
-	dsn := "host=localhost user=postgres password=12345678 dbname=Alumni_db port=5432 sslmode=disable"
-
-	DB, err = sql.Open("postgres", dsn)
+	DB, err = sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		log.Fatal("Gagal koneksi ke database:", err)
 	}
@@ -23,4 +22,4 @@ func ConnectDB() {
 		log.Fatal("Gagal ping database:", err)
 	}
 	fmt.Println("Berhasil terhubung ke database PostgreSQL")
-}
\ No newline at end of file
+}
@@ -0,0 +1,300 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID -> kunci advisory Postgres supaya dua proses (mis. dua instance
+// yang start bersamaan) tidak menjalankan migrasi yang sama
+const migrationLockID = 727_001
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration -> satu versi skema; Down kosong berarti tidak bisa di-rollback
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus -> migrasi beserta waktu diterapkan, nil kalau belum
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// LoadMigrations -> migrasi yang ikut di-embed ke binary, urut versi
+func LoadMigrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		// File .sql dengan nama yang salah akan diam-diam tidak pernah dijalankan
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("nama file migrasi %q tidak sesuai format NNNN_nama.up.sql/.down.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("versi migrasi %04d dipakai dua nama: %s dan %s", version, m.Name, match[2])
+		}
+		target := &m.Up
+		if match[3] == "down" {
+			target = &m.Down
+		}
+		if *target != "" {
+			return nil, fmt.Errorf("migrasi %04d_%s punya lebih dari satu file .%s.sql", version, m.Name, match[3])
+		}
+		*target = string(body)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrasi %04d_%s tidak punya file .up.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator -> menerapkan migrasi dan mencatatnya di schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// MigrateUp -> terapkan semua migrasi yang belum jalan, dipakai saat server start
+func MigrateUp(db *sql.DB) ([]Migration, error) {
+	m, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	return m.Up(0)
+}
+
+// Up -> terapkan migrasi yang belum jalan, paling banyak limit (0 = semua)
+func (m *Migrator) Up(limit int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(func(conn *sql.Conn, applied map[int]time.Time) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if limit > 0 && len(done) == limit {
+				break
+			}
+			if err := m.apply(conn, mig, mig.Up, true); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down -> rollback steps migrasi terakhir yang sudah diterapkan
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(func(conn *sql.Conn, applied map[int]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migrasi %04d_%s tidak punya file .down.sql", mig.Version, mig.Name)
+			}
+			if err := m.apply(conn, mig, mig.Down, false); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Baseline -> tandai migrasi sampai version sebagai sudah diterapkan tanpa
+// menjalankannya, untuk database lama yang skemanya dibuat manual
+func (m *Migrator) Baseline(version int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(func(conn *sql.Conn, applied map[int]time.Time) error {
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if _, err := conn.ExecContext(context.Background(),
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status -> semua migrasi yang dikenal binary beserta status penerapannya
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(func(conn *sql.Conn, applied map[int]time.Time) error {
+		for _, mig := range m.migrations {
+			status := MigrationStatus{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock -> semua operasi memakai satu koneksi yang memegang advisory lock
+func (m *Migrator) withLock(fn func(conn *sql.Conn, applied map[int]time.Time) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`); err != nil {
+		return err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// apply -> isi migrasi dan catatan versinya berada di satu transaksi, jadi
+// migrasi yang gagal di tengah jalan tidak meninggalkan skema setengah jadi
+func (m *Migrator) apply(conn *sql.Conn, mig Migration, body string, up bool) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		direction := "up"
+		if !up {
+			direction = "down"
+		}
+		return fmt.Errorf("migrasi %04d_%s (%s) gagal: %w", mig.Version, mig.Name, direction, err)
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateMigration -> buat pasangan file up/down kosong dengan versi berikutnya di dir
+func CreateMigration(dir, name string) (string, string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("nama migrasi kosong")
+	}
+
+	existing, err := loadMigrations(os.DirFS(dir), ".")
+	if err != nil {
+		return "", "", err
+	}
+	next := 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", next, name))
+	up, down := base+".up.sql", base+".down.sql"
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- rollback "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// migrationFS -> folder migrations palsu; isi tiap file adalah namanya sendiri
+func migrationFS(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		fsys["migrations/"+name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	return fsys
+}
+
+func TestLoadMigrationsOrdersByVersion(t *testing.T) {
+	fsys := migrationFS(
+		"0010_api_keys.up.sql", "0010_api_keys.down.sql",
+		"0002_roles.up.sql", "0002_roles.down.sql",
+		"0000_base.up.sql", "0000_base.down.sql",
+		"README.md",
+	)
+	migrations, err := loadMigrations(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, m := range migrations {
+		got = append(got, fmt.Sprintf("%d_%s", m.Version, m.Name))
+		base := fmt.Sprintf("%04d_%s", m.Version, m.Name)
+		if m.Up != base+".up.sql" || m.Down != base+".down.sql" {
+			t.Errorf("isi migrasi %s tertukar: up %q, down %q", base, m.Up, m.Down)
+		}
+	}
+	// Versi dibandingkan sebagai angka, file selain .sql diabaikan
+	if want := "0_base,2_roles,10_api_keys"; strings.Join(got, ",") != want {
+		t.Errorf("urutan migrasi %v, seharusnya %s", got, want)
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string // potongan pesan error
+	}{
+		{
+			name:  "tanpa .up.sql",
+			files: []string{"0001_users.up.sql", "0002_roles.down.sql"},
+			want:  "0002_roles tidak punya file .up.sql",
+		},
+		{
+			name:  "satu versi dua nama",
+			files: []string{"0003_api_keys.up.sql", "0003_oidc.up.sql"},
+			want:  "versi migrasi 0003 dipakai dua nama",
+		},
+		{
+			name:  "satu versi ditulis dua kali",
+			files: []string{"0003_oidc.up.sql", "3_oidc.up.sql"},
+			want:  "0003_oidc punya lebih dari satu file .up.sql",
+		},
+		{
+			name:  "nama file salah",
+			files: []string{"0001_users.up.sql", "0002_add-roles.up.sql"},
+			want:  `"0002_add-roles.up.sql" tidak sesuai format`,
+		},
+		{
+			name:  "tanpa arah up/down",
+			files: []string{"0001_users.sql"},
+			want:  `"0001_users.sql" tidak sesuai format`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(migrationFS(tt.files...), "migrations")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, seharusnya memuat %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationsWithoutDown(t *testing.T) {
+	// Migrasi tanpa .down.sql tetap bisa diterapkan, hanya tidak bisa di-rollback
+	migrations, err := loadMigrations(migrationFS("0001_users.up.sql", "0001_users.down.sql", "0002_seed.up.sql"), "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[1].Down != "" {
+		t.Errorf("migrasi 0002 seharusnya dimuat tanpa down: %+v", migrations)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != i {
+			t.Fatalf("versi migrasi bolong atau loncat: urutan ke-%d bernomor %04d", i, m.Version)
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migrasi %04d_%s tidak punya .down.sql", m.Version, m.Name)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		input    string
+		wantBase string
+	}{
+		{"folder kosong", nil, "users", "0001_users"},
+		{"setelah versi terakhir", []string{"0000_base.up.sql", "0009_two_factor.up.sql", "0010_api_keys.up.sql"}, "oidc", "0011_oidc"},
+		{"nama dinormalkan", []string{"0004_x.up.sql"}, "  Add User-Devices! ", "0005_add_user_devices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("-- "+name), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			up, down, err := CreateMigration(dir, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, tt.wantBase+".up.sql"); up != want {
+				t.Errorf("file up %s, seharusnya %s", up, want)
+			}
+			if want := filepath.Join(dir, tt.wantBase+".down.sql"); down != want {
+				t.Errorf("file down %s, seharusnya %s", down, want)
+			}
+			// Pasangan file baru harus langsung terbaca sebagai migrasi yang sah
+			if _, err := loadMigrations(os.DirFS(dir), "."); err != nil {
+				t.Errorf("migrasi baru tidak bisa dimuat: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateMigrationRejectsEmptyName(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := CreateMigration(dir, "--!"); err == nil {
+		t.Error("nama kosong seharusnya ditolak")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("tidak boleh ada file dibuat, ada %d", len(entries))
+	}
+}
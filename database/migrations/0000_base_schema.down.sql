@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pekerjaan;
+DROP TABLE IF EXISTS alumni;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,47 @@
+-- Skema dasar yang sebelumnya hanya ada di database lokal. Semua pernyataan
+-- idempoten supaya aman dijalankan di database lama yang tabelnya sudah ada.
+CREATE TABLE IF NOT EXISTS users (
+    id            SERIAL PRIMARY KEY,
+    username      VARCHAR(50)  NOT NULL UNIQUE,
+    email         VARCHAR(100) NOT NULL UNIQUE,
+    password_hash TEXT         NOT NULL,
+    role          VARCHAR(50)  NOT NULL DEFAULT 'user',
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS alumni (
+    id          SERIAL PRIMARY KEY,
+    nim         VARCHAR(20)  NOT NULL UNIQUE,
+    nama        VARCHAR(100) NOT NULL,
+    jurusan     VARCHAR(100) NOT NULL,
+    angkatan    INT          NOT NULL,
+    tahun_lulus INT          NOT NULL,
+    email       VARCHAR(100) NOT NULL,
+    no_telepon  VARCHAR(20),
+    alamat      TEXT,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS pekerjaan (
+    id                    SERIAL PRIMARY KEY,
+    alumni_id             INT          NOT NULL REFERENCES alumni(id) ON DELETE CASCADE,
+    nama_perusahaan       VARCHAR(100) NOT NULL,
+    posisi_jabatan        VARCHAR(100) NOT NULL,
+    bidang_industri       VARCHAR(100) NOT NULL,
+    lokasi_kerja          VARCHAR(100) NOT NULL,
+    gaji_range            VARCHAR(50),
+    tanggal_mulai_kerja   DATE         NOT NULL,
+    tanggal_selesai_kerja DATE,
+    status_pekerjaan      VARCHAR(20)  NOT NULL DEFAULT 'aktif',
+    deskripsi_pekerjaan   TEXT,
+    created_at            TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at            TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+-- Kolom soft delete ditambahkan belakangan di database lama
+ALTER TABLE pekerjaan ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE pekerjaan ADD COLUMN IF NOT EXISTS created_by VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_pekerjaan_alumni_id ON pekerjaan (alumni_id);
+CREATE INDEX IF NOT EXISTS idx_pekerjaan_is_deleted ON pekerjaan (is_deleted);
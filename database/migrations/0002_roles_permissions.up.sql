@@ -0,0 +1,70 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name        VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name        VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role       VARCHAR(50)  NOT NULL REFERENCES roles(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Administrator sistem, akses penuh'),
+    ('operator_prodi', 'Operator program studi, kelola data alumni dan pekerjaan'),
+    ('user', 'Alumni, kelola data milik sendiri')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('alumni:read', 'Lihat data alumni'),
+    ('alumni:create', 'Tambah data alumni'),
+    ('alumni:write', 'Ubah data alumni'),
+    ('alumni:delete', 'Hapus data alumni'),
+    ('pekerjaan:read', 'Lihat data pekerjaan'),
+    ('pekerjaan:write', 'Tambah dan ubah data pekerjaan'),
+    ('pekerjaan:delete', 'Soft delete data pekerjaan'),
+    ('pekerjaan:restore', 'Restore data pekerjaan dari trash'),
+    ('pekerjaan:hard_delete', 'Hapus permanen data pekerjaan'),
+    ('pekerjaan:manage', 'Kelola pekerjaan milik alumni lain'),
+    ('users:manage', 'Kelola akun user dan sesinya')
+ON CONFLICT (name) DO NOTHING;
+
+-- Admin mendapat semua permission
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('operator_prodi', 'alumni:read'),
+    ('operator_prodi', 'alumni:create'),
+    ('operator_prodi', 'alumni:write'),
+    ('operator_prodi', 'pekerjaan:read'),
+    ('operator_prodi', 'pekerjaan:write'),
+    ('operator_prodi', 'pekerjaan:delete'),
+    ('operator_prodi', 'pekerjaan:restore'),
+    ('operator_prodi', 'pekerjaan:manage')
+ON CONFLICT DO NOTHING;
+
+-- Role lain yang sudah dipakai di tabel users mendapat hak setara 'user'
+INSERT INTO roles (name)
+SELECT DISTINCT role FROM users WHERE role IS NOT NULL
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission)
+SELECT r.name, p.permission
+FROM roles r
+CROSS JOIN (VALUES
+    ('alumni:read'),
+    ('alumni:write'),
+    ('pekerjaan:read'),
+    ('pekerjaan:write'),
+    ('pekerjaan:delete'),
+    ('pekerjaan:restore')
+) AS p(permission)
+WHERE r.name NOT IN ('admin', 'operator_prodi')
+ON CONFLICT DO NOTHING;
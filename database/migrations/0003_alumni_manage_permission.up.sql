@@ -0,0 +1,8 @@
+INSERT INTO permissions (name, description) VALUES
+    ('alumni:manage', 'Akses data alumni milik siapa pun, bukan hanya milik sendiri')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'alumni:manage'),
+    ('operator_prodi', 'alumni:manage')
+ON CONFLICT DO NOTHING;
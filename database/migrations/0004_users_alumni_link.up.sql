@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS alumni_id INT UNIQUE REFERENCES alumni(id) ON DELETE SET NULL;
+
+-- Isi link awal hanya kalau email user cocok dengan tepat satu alumni dan
+-- tidak dipakai user lain; sisanya dihubungkan manual oleh admin
+UPDATE users u SET alumni_id = a.id
+FROM alumni a
+WHERE u.alumni_id IS NULL
+  AND LOWER(u.email) = LOWER(a.email)
+  AND NOT EXISTS (SELECT 1 FROM users u2 WHERE u2.id <> u.id AND LOWER(u2.email) = LOWER(u.email))
+  AND (SELECT COUNT(*) FROM alumni a2 WHERE LOWER(a2.email) = LOWER(a.email)) = 1;
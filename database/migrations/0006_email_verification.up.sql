@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- Akun yang sudah ada dibuat admin, jadi dianggap sudah terverifikasi
+UPDATE users SET email_verified_at = NOW() WHERE email_verified_at IS NULL;
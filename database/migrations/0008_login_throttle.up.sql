@@ -0,0 +1,9 @@
+-- Hitungan login gagal per akun ('user:<username>') dan per alamat ('ip:<address>')
+CREATE TABLE IF NOT EXISTS login_throttle (
+    key             TEXT PRIMARY KEY,
+    failures        INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttle_locked_until ON login_throttle (locked_until);
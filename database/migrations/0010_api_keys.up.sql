@@ -0,0 +1,22 @@
+-- API key untuk integrasi antar sistem (script laporan, sinkronisasi SIAKAD).
+-- Hanya hash yang disimpan; prefix dipakai untuk mencari key tanpa membocorkan secret.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           SERIAL PRIMARY KEY,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL UNIQUE,
+    key_hash     TEXT NOT NULL,
+    scopes       TEXT[] NOT NULL,
+    created_by   INT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip TEXT,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('api_keys:manage', 'Buat, lihat, dan cabut API key integrasi')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'api_keys:manage')
+ON CONFLICT DO NOTHING;
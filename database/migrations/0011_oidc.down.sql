@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS oidc_login_states;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_subject;
@@ -0,0 +1,11 @@
+-- Subject dari identity provider; satu subject hanya untuk satu akun
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT UNIQUE;
+
+-- state, nonce, dan PKCE verifier selama user berada di halaman login IdP
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state         TEXT PRIMARY KEY,
+    nonce         TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
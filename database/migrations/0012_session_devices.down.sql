@@ -0,0 +1,3 @@
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS device;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS ip;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,5 @@
+-- Info perangkat per sesi untuk halaman "sesi aktif"; ip dan user_agent
+-- diperbarui setiap kali sesi dipakai, device diturunkan dari user_agent
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS device TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Naik setiap kali role atau status akun berubah; access token dengan versi
+-- lama langsung ditolak walaupun belum expired
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,33 @@
+-- Jejak audit; tidak memakai foreign key supaya catatan tetap utuh walaupun
+-- user atau data yang dirujuk sudah dihapus
+CREATE TABLE IF NOT EXISTS audit_log (
+    id                    BIGSERIAL PRIMARY KEY,
+    occurred_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    action                TEXT NOT NULL,
+    actor_user_id         INT,
+    actor_username        TEXT NOT NULL DEFAULT '',
+    impersonator_user_id  INT,
+    impersonator_username TEXT NOT NULL DEFAULT '',
+    auth_type             TEXT NOT NULL DEFAULT '',
+    target_type           TEXT NOT NULL DEFAULT '',
+    target_id             TEXT NOT NULL DEFAULT '',
+    method                TEXT NOT NULL DEFAULT '',
+    path                  TEXT NOT NULL DEFAULT '',
+    status                INT,
+    ip                    TEXT NOT NULL DEFAULT '',
+    user_agent            TEXT NOT NULL DEFAULT '',
+    request_id            TEXT NOT NULL DEFAULT '',
+    details               JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_impersonator ON audit_log (impersonator_user_id)
+    WHERE impersonator_user_id IS NOT NULL;
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:impersonate', 'Login sementara sebagai user lain untuk membantu helpdesk')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'users:impersonate')
+ON CONFLICT DO NOTHING;
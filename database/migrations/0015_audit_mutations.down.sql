@@ -0,0 +1,9 @@
+DELETE FROM permissions WHERE name = 'audit:read';
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+DROP TRIGGER IF EXISTS audit_log_no_modify ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+DROP INDEX IF EXISTS idx_audit_log_request_id;
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_target;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS after_data;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS before_data;
@@ -0,0 +1,33 @@
+-- Snapshot data sebelum/sesudah perubahan untuk audit create/update/delete
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS before_data JSONB;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS after_data JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_request_id ON audit_log (request_id)
+    WHERE request_id <> '';
+
+-- audit_log hanya boleh ditambah; UPDATE, DELETE dan TRUNCATE selalu ditolak,
+-- termasuk dari aplikasi sendiri
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log bersifat append-only: % tidak diizinkan', TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_modify ON audit_log;
+CREATE TRIGGER audit_log_no_modify
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit:read', 'Melihat jejak audit perubahan data')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'audit:read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DELETE FROM permissions WHERE name = 'revisions:revert';
+DROP TABLE IF EXISTS revisions;
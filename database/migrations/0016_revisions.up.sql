@@ -0,0 +1,23 @@
+-- Snapshot lengkap setiap versi data alumni/pekerjaan; revisi 1 adalah data
+-- pertama yang tercatat (create, atau kondisi sebelum perubahan pertama untuk
+-- data lama). Sama seperti audit_log, tidak ada foreign key ke data asal.
+CREATE TABLE IF NOT EXISTS revisions (
+    id                  BIGSERIAL PRIMARY KEY,
+    entity_type         TEXT NOT NULL,
+    entity_id           INT NOT NULL,
+    revision            INT NOT NULL,
+    action              TEXT NOT NULL,
+    data                JSONB NOT NULL,
+    changed_by_user_id  INT,
+    changed_by          TEXT NOT NULL DEFAULT '',
+    request_id          TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (entity_type, entity_id, revision)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('revisions:revert', 'Mengembalikan data alumni/pekerjaan ke revisi sebelumnya')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'revisions:revert')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Akun lokal yang boleh login lewat LDAP: dibuat dari direktori, atau dihubungkan
+-- admin lewat PUT /admin/users/:id/ldap. Akun LDAP yang dibuat sebelum kolom ini
+-- ada perlu dihubungkan ulang oleh admin.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ldap_linked BOOLEAN NOT NULL DEFAULT FALSE;
@@ -2,42 +2,80 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"tugas5/app/repository"
 	"tugas5/config"
 	"tugas5/database"
+	"tugas5/middleware"
 	"tugas5/routes"
+	"tugas5/utils"
 
 	"github.com/gofiber/fiber/v2"
-	
 )
 
+// shutdownTimeout -> batas tunggu request yang masih berjalan saat server dihentikan
+const shutdownTimeout = 15 * time.Second
+
 func main() {
-	// Load environment variables
-	config.LoadEnv()
+	// Load dan validasi konfigurasi
+	cfg, err := config.LoadEnv()
+	if err != nil {
+		log.Fatal("Konfigurasi tidak valid: ", err)
+	}
 
 	// Connect to database
-	database.ConnectDB()
+	database.ConnectDB(cfg.Database)
 	defer database.DB.Close()
+	repository.SetQueryTimeouts(cfg.Database.ReadTimeout, cfg.Database.WriteTimeout)
+
+	if cfg.Database.AutoMigrate {
+		applied, err := database.MigrateUp(database.DB)
+		if err != nil {
+			log.Fatal("Migrasi database gagal: ", err)
+		}
+		for _, m := range applied {
+			log.Printf("Migrasi %04d_%s diterapkan", m.Version, m.Name)
+		}
+	}
+
+	if err := utils.InitJWT(cfg.JWT); err != nil {
+		log.Fatal("Gagal menyiapkan kunci JWT: ", err)
+	}
 
 	// Fiber app dengan custom error handler
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			return c.Status(500).JSON(fiber.Map{
-				"error": err.Error(),
+			return c.Status(middleware.DBStatus(err)).JSON(fiber.Map{
+				"error": middleware.DBErrorMessage(err),
 			})
 		},
 	})
 
 	// Middleware global
+	app.Use(middleware.RequestID())
+	app.Use(middleware.RequestContext())
 	app.Use(config.LoggerMiddleware())
 
 	// Setup routes
-	routes.UserRoutes(app)
+	routes.UserRoutes(app, cfg)
 
-	// Get port dari env (default 3000)
-	port := config.GetEnv("APP_PORT", "3000")
-
-	log.Println("Server running on port " + port)
-	log.Fatal(app.Listen(":" + port))
+	go func() {
+		log.Printf("Server running on port %s (%s)", cfg.App.Port, cfg.App.Env)
+		if err := app.Listen(":" + cfg.App.Port); err != nil {
+			log.Fatal(err)
+		}
+	}()
 
+	// SIGINT/SIGTERM: berhenti menerima request baru dan membatalkan context
+	// request yang masih berjalan, sehingga query-nya ikut dihentikan
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Println("Server berhenti, menunggu request yang masih berjalan")
+	if err := app.ShutdownWithTimeout(shutdownTimeout); err != nil {
+		log.Println("Shutdown server tidak selesai:", err)
+	}
 }
-
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+	"tugas5/app/repository"
+	"tugas5/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	authTypeUser   = "user"
+	authTypeAPIKey = "api_key"
+)
+
+// authenticateAPIKey -> API key tidak terikat ke akun user; permission-nya
+// adalah scope key itu sendiri, jadi Require bekerja sama seperti untuk JWT
+func authenticateAPIKey(c *fiber.Ctx, repo repository.APIKeyRepository, rawKey string) error {
+	prefix, ok := utils.ParseAPIKey(rawKey)
+	if !ok || repo == nil {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "API key tidak valid",
+		})
+	}
+
+	key, err := repo.GetByPrefix(c.UserContext(), prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "API key tidak valid",
+			})
+		}
+		return c.Status(DBStatus(err)).JSON(fiber.Map{
+			"error": "Gagal memeriksa API key",
+		})
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(rawKey)), []byte(key.KeyHash)) != 1 {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "API key tidak valid",
+		})
+	}
+	if key.RevokedAt != nil || time.Now().After(key.ExpiresAt) {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "API key sudah dicabut atau expired",
+		})
+	}
+
+	if err := repo.TouchLastUsed(c.UserContext(), key.ID, c.IP()); err != nil {
+		log.Println("Gagal mencatat pemakaian API key:", err)
+	}
+
+	perms := make(map[string]bool, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		perms[scope] = true
+	}
+
+	c.Locals("auth_type", authTypeAPIKey)
+	c.Locals("api_key_id", key.ID)
+	c.Locals("user_id", 0)
+	c.Locals("username", "apikey:"+key.Name)
+	c.Locals("role", authTypeAPIKey)
+	c.Locals("permissions", perms)
+	return c.Next()
+}
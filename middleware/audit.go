@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"tugas5/app/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewAuditEntry -> entri audit berisi pelaku dan asal request dari context;
+// pemanggil tinggal mengisi target dan data yang berubah
+func NewAuditEntry(c *fiber.Ctx, action string) *model.AuditEntry {
+	entry := &model.AuditEntry{
+		Action:    action,
+		Method:    c.Method(),
+		Path:      c.OriginalURL(),
+		IP:        c.IP(),
+		UserAgent: ClientInfo(c).UserAgent,
+		RequestID: CurrentRequestID(c),
+	}
+	entry.AuthType, _ = c.Locals("auth_type").(string)
+	entry.ActorUsername, _ = c.Locals("username").(string)
+	// API key tidak punya user, user_id di Locals bernilai 0
+	if userID, ok := c.Locals("user_id").(int); ok && userID != 0 {
+		entry.ActorUserID = &userID
+	}
+	if impersonatorID, ok := c.Locals("impersonator_id").(int); ok {
+		entry.ImpersonatorUserID = &impersonatorID
+		entry.ImpersonatorUsername, _ = c.Locals("impersonator_username").(string)
+	}
+	return entry
+}
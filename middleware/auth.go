@@ -1,16 +1,39 @@
 package middleware
 
 import (
+	"database/sql"
+	"errors"
+	"log"
 	"strings"
-	"tugas5/app/model" // Import struct JWTClaims
+	"tugas5/app/model"
+	"tugas5/app/repository"
+	"tugas5/utils"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte("secret_key") // HARUS sama persis dengan utils
+// AuthConfig -> dependency yang dibutuhkan AuthRequired
+type AuthConfig struct {
+	Users       repository.CachedUserRepository
+	Sessions    repository.SessionRepository
+	Permissions repository.PermissionRepository
+	APIKeys     repository.APIKeyRepository
+	Audit       repository.AuditRepository
+
+	// PasswordChangePath -> satu-satunya route (POST) yang boleh diakses user
+	// yang password-nya direset admin sebelum password itu diganti
+	PasswordChangePath string
+}
+
+func AuthRequired(cfg AuthConfig) fiber.Handler {
+	permissions := newPermissionCache(cfg.Permissions)
 
-func AuthRequired() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// Integrasi boleh mengirim API key lewat X-API-Key atau sebagai bearer token
+		if key := c.Get("X-API-Key"); key != "" {
+			return authenticateAPIKey(c, cfg.APIKeys, key)
+		}
+
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return c.Status(401).JSON(fiber.Map{
@@ -24,22 +47,162 @@ func AuthRequired() fiber.Handler {
 				"error": "Format token tidak valid",
 			})
 		}
+		if _, isKey := utils.ParseAPIKey(tokenParts[1]); isKey {
+			return authenticateAPIKey(c, cfg.APIKeys, tokenParts[1])
+		}
 
-		claims := &model.JWTClaims{}
-		token, err := jwt.ParseWithClaims(tokenParts[1], claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-		if err != nil || !token.Valid {
+		claims, err := utils.ValidateToken(tokenParts[1])
+		// Token tanpa jti tidak bisa dicabut, jadi ikut ditolak
+		if err != nil || claims.ID == "" {
 			return c.Status(401).JSON(fiber.Map{
 				"error": "Token tidak valid atau expired",
 			})
 		}
 
+		revoked, err := cfg.Sessions.IsTokenRevoked(c.UserContext(), claims.ID)
+		if err != nil {
+			return c.Status(DBStatus(err)).JSON(fiber.Map{
+				"error": "Gagal memeriksa status token",
+			})
+		}
+		if revoked {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Token sudah dicabut",
+			})
+		}
+
+		// Status, role, dan link alumni dibaca dari data user terkini (lewat cache
+		// singkat), bukan dari token, supaya perubahan oleh admin langsung berlaku
+		user, err := cfg.Users.GetCached(c.UserContext(), claims.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Status(401).JSON(fiber.Map{
+					"error": "User tidak ditemukan",
+				})
+			}
+			return c.Status(DBStatus(err)).JSON(fiber.Map{
+				"error": "Gagal memuat data user",
+			})
+		}
+		if !user.IsActive {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Akun dinonaktifkan",
+			})
+		}
+		// Role atau status akun berubah setelah token dibuat
+		if claims.Version != user.TokenVersion {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Token sudah tidak berlaku, silakan refresh atau login ulang",
+			})
+		}
+
+		// Token impersonasi hanya berlaku selama admin pembuatnya masih aktif
+		// dan masih punya izin impersonasi
+		var impersonator *model.User
+		if claims.Act != nil {
+			impersonator, err = cfg.Users.GetCached(c.UserContext(), claims.Act.UserID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return c.Status(DBStatus(err)).JSON(fiber.Map{
+					"error": "Gagal memuat data user",
+				})
+			}
+			if impersonator == nil || !impersonator.IsActive {
+				return c.Status(401).JSON(fiber.Map{
+					"error": "Admin yang melakukan impersonasi sudah tidak aktif",
+				})
+			}
+			adminPerms, err := permissions.forRole(c.UserContext(), impersonator.Role)
+			if err != nil {
+				return c.Status(DBStatus(err)).JSON(fiber.Map{
+					"error": "Gagal memuat hak akses",
+				})
+			}
+			if !adminPerms[permImpersonate] {
+				return c.Status(401).JSON(fiber.Map{
+					"error": "Izin impersonasi admin sudah dicabut",
+				})
+			}
+		}
+
+		if claims.SessionID != "" {
+			if err := cfg.Sessions.Touch(c.UserContext(), claims.SessionID, ClientInfo(c)); err != nil {
+				log.Println("Gagal memperbarui waktu terakhir sesi:", err)
+			}
+		}
+
+		perms, err := permissions.forRole(c.UserContext(), user.Role)
+		if err != nil {
+			return c.Status(DBStatus(err)).JSON(fiber.Map{
+				"error": "Gagal memuat hak akses",
+			})
+		}
+
+		// Password sementara dari admin harus diganti dulu. Admin yang sedang
+		// impersonasi tidak bisa mengganti password user, jadi tidak ikut ditahan.
+		if user.MustChangePassword && impersonator == nil &&
+			!(c.Method() == fiber.MethodPost && c.Path() == cfg.PasswordChangePath) {
+			return c.Status(403).JSON(fiber.Map{
+				"error":                "Password harus diganti sebelum melanjutkan",
+				"must_change_password": true,
+			})
+		}
+
 		// Simpan user info di context
-		c.Locals("user_id", claims.UserID)
-		c.Locals("username", claims.Username)
-		c.Locals("role", claims.Role)
+		c.Locals("auth_type", authTypeUser)
+		c.Locals("user_id", user.ID)
+		c.Locals("username", user.Username)
+		c.Locals("role", user.Role)
+		c.Locals("permissions", perms)
+		if user.AlumniID != nil {
+			c.Locals("alumni_id", *user.AlumniID)
+		}
+		c.Locals("session_id", claims.SessionID)
+		c.Locals("jti", claims.ID)
+		c.Locals("token_expires_at", claims.ExpiresAt.Time)
 
+		if impersonator == nil {
+			return c.Next()
+		}
+		c.Locals("impersonator_id", impersonator.ID)
+		c.Locals("impersonator_username", impersonator.Username)
+		c.Set("X-Impersonated-By", impersonator.Username)
+		err = c.Next()
+		recordImpersonatedRequest(c, cfg.Audit, err)
+		return err
+	}
+}
+
+// maxUserAgentLength -> User-Agent yang lebih panjang dipotong sebelum disimpan
+const maxUserAgentLength = 512
+
+// ClientInfo -> perangkat dan alamat asal request, disimpan bersama sesi login
+func ClientInfo(c *fiber.Ctx) model.SessionClient {
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return model.SessionClient{
+		UserAgent: userAgent,
+		IP:        c.IP(),
+		Device:    utils.DeviceLabel(userAgent),
+	}
+}
+
+// HumanOnly -> tolak API key di route yang hanya masuk akal untuk akun manusia
+// (profil, password, sesi, 2FA)
+func HumanOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("auth_type") != authTypeUser {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Route ini tidak bisa diakses dengan API key",
+			})
+		}
 		return c.Next()
 	}
 }
+
+// CurrentAlumniID -> id alumni yang terhubung dengan akun saat ini
+func CurrentAlumniID(c *fiber.Ctx) (int, bool) {
+	id, ok := c.Locals("alumni_id").(int)
+	return id, ok
+}
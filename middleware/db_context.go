@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"tugas5/app/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestContext -> context yang diteruskan handler ke repository. Dibatalkan
+// kalau klien memutus koneksi sebelum handler selesai (lihat watchDisconnect)
+// atau saat server shutdown, sehingga query milik klien yang sudah pergi ikut
+// dihentikan; batas waktu per query dari repository tetap berlaku.
+func RequestContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(c.Context())
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		stop := watchDisconnect(c.Context().Conn(), cancel)
+		defer stop()
+		return c.Next()
+	}
+}
+
+// DBStatus -> status HTTP untuk error dari repository: 504 kalau query
+// melewati batas waktu, 503 kalau request dibatalkan (klien memutus koneksi
+// atau server berhenti), selain itu 500
+func DBStatus(err error) int {
+	switch {
+	case repository.IsTimeout(err):
+		return fiber.StatusGatewayTimeout
+	case repository.IsCanceled(err):
+		return fiber.StatusServiceUnavailable
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+// DBErrorMessage -> pesan untuk klien; error timeout/batal diganti pesan umum
+// supaya detail driver tidak ikut terkirim
+func DBErrorMessage(err error) string {
+	switch DBStatus(err) {
+	case fiber.StatusGatewayTimeout:
+		return "Database tidak merespons tepat waktu"
+	case fiber.StatusServiceUnavailable:
+		return "Request dibatalkan sebelum selesai, coba lagi"
+	default:
+		return err.Error()
+	}
+}
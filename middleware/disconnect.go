@@ -0,0 +1,87 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+// disconnectPollInterval -> seberapa sering koneksi klien dicek selama handler berjalan
+const disconnectPollInterval = 100 * time.Millisecond
+
+// watchDisconnect -> fasthttp tidak membaca koneksi selama handler berjalan,
+// jadi koneksi diintip (MSG_PEEK, tanpa mengambil data) secara berkala; EOF
+// atau reset berarti klien sudah pergi dan cancel dipanggil. stop harus
+// dipanggil sebelum handler kembali ke fasthttp.
+func watchDisconnect(conn net.Conn, cancel context.CancelFunc) (stop func()) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return func() {}
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			switch peekConn(raw) {
+			case connClosed:
+				cancel()
+				return
+			case connHasData:
+				// Request berikutnya (pipelining) sudah masuk; status koneksi
+				// tidak bisa dibedakan lagi, jadi pengecekan dihentikan
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+type connState int
+
+const (
+	connOpen connState = iota
+	connHasData
+	connClosed
+)
+
+func peekConn(raw syscall.RawConn) connState {
+	state := connOpen
+	var buf [1]byte
+	err := raw.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EWOULDBLOCK), errors.Is(err, syscall.EINTR):
+			state = connOpen
+		case err != nil, n == 0:
+			state = connClosed
+		default:
+			state = connHasData
+		}
+		// Selalu true supaya Read tidak menunggu koneksi bisa dibaca
+		return true
+	})
+	if err != nil {
+		return connClosed
+	}
+	return state
+}
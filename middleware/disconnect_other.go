@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package middleware
+
+import (
+	"context"
+	"net"
+)
+
+// watchDisconnect -> di platform ini koneksi tidak bisa diintip tanpa mengambil
+// data, jadi query tetap dibatasi timeout repository dan shutdown server saja
+func watchDisconnect(conn net.Conn, cancel context.CancelFunc) (stop func()) {
+	return func() {}
+}
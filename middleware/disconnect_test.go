@@ -0,0 +1,94 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// startContextApp -> server sungguhan (bukan app.Test) supaya koneksi TCP
+// benar-benar bisa diputus klien; hasil ctx.Err() handler dikirim ke results
+func startContextApp(t *testing.T, wait time.Duration) (string, <-chan error) {
+	t.Helper()
+	results := make(chan error, 1)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(RequestContext())
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		select {
+		case <-c.UserContext().Done():
+		case <-time.After(wait):
+		}
+		results <- c.UserContext().Err()
+		return c.SendString("selesai")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	// Cukup tutup listener; app.Shutdown saat request berjalan memicu race
+	// di dalam fasthttp (RequestCtx.Done vs Server.Shutdown)
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().String(), results
+}
+
+func TestRequestContextCanceledWhenClientDisconnects(t *testing.T) {
+	addr, results := startContextApp(t, 5*time.Second)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * disconnectPollInterval)
+	conn.Close()
+
+	select {
+	case err := <-results:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ctx.Err() = %v, seharusnya context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("context tidak dibatalkan setelah klien memutus koneksi")
+	}
+}
+
+func TestRequestContextKeepsConnectedClient(t *testing.T) {
+	addr, results := startContextApp(t, 3*disconnectPollInterval)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// Dua request berurutan di koneksi keep-alive yang sama tetap dijawab
+	for i := 0; i < 2; i++ {
+		if _, err := conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("request ke-%d: %v", i+1, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Errorf("request ke-%d: status %d", i+1, resp.StatusCode)
+		}
+		if err := <-results; err != nil {
+			t.Errorf("request ke-%d: ctx.Err() = %v, klien masih terhubung", i+1, err)
+		}
+	}
+}
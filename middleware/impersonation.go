@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"tugas5/app/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// permImpersonate -> admin pembuat token impersonasi harus tetap punya izin ini
+const permImpersonate = "users:impersonate"
+
+// NotImpersonating -> tolak aksi yang mengubah data kalau token adalah hasil
+// impersonasi; admin harus memakai tokennya sendiri untuk membuat, mengubah, atau menghapus data
+func NotImpersonating() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if IsImpersonating(c) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Aksi ini tidak diizinkan selama impersonasi",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// IsImpersonating -> apakah request ini memakai token impersonasi
+func IsImpersonating(c *fiber.Ctx) bool {
+	_, ok := c.Locals("impersonator_id").(int)
+	return ok
+}
+
+// recordImpersonatedRequest -> setiap request selama impersonasi dicatat,
+// termasuk yang ditolak, supaya jelas apa saja yang dilihat admin
+func recordImpersonatedRequest(c *fiber.Ctx, audit repository.AuditRepository, handlerErr error) {
+	status := c.Response().StatusCode()
+	var fe *fiber.Error
+	if errors.As(handlerErr, &fe) {
+		status = fe.Code
+	} else if handlerErr != nil {
+		status = fiber.StatusInternalServerError
+	}
+
+	entry := NewAuditEntry(c, "impersonation.request")
+	entry.Status = &status
+	if err := audit.Record(c.UserContext(), entry); err != nil {
+		log.Println("Gagal mencatat audit impersonasi:", err)
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+	"tugas5/app/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// permissionCacheTTL -> perubahan role_permissions di database berlaku paling lambat setelah ini
+const permissionCacheTTL = time.Minute
+
+// Require -> lanjutkan request hanya kalau user punya semua permission yang disebut.
+// Dipasang setelah AuthRequired, yang mengisi daftar permission ke context.
+func Require(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, perm := range perms {
+			if !HasPermission(c, perm) {
+				return c.Status(403).JSON(fiber.Map{
+					"error":      "Anda tidak memiliki izin untuk aksi ini",
+					"permission": perm,
+				})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// HasPermission -> cek permission user saat ini, untuk aturan yang bergantung pada data
+func HasPermission(c *fiber.Ctx, perm string) bool {
+	perms, _ := c.Locals("permissions").(map[string]bool)
+	return perms[perm]
+}
+
+type cachedPermissions struct {
+	perms    map[string]bool
+	loadedAt time.Time
+}
+
+// permissionCache -> simpan permission per role di memori supaya tidak query tiap request
+type permissionCache struct {
+	repo    repository.PermissionRepository
+	mu      sync.RWMutex
+	entries map[string]cachedPermissions
+}
+
+func newPermissionCache(repo repository.PermissionRepository) *permissionCache {
+	return &permissionCache{repo: repo, entries: map[string]cachedPermissions{}}
+}
+
+func (pc *permissionCache) forRole(ctx context.Context, role string) (map[string]bool, error) {
+	pc.mu.RLock()
+	entry, ok := pc.entries[role]
+	pc.mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < permissionCacheTTL {
+		return entry.perms, nil
+	}
+
+	list, err := pc.repo.GetByRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	perms := make(map[string]bool, len(list))
+	for _, p := range list {
+		perms[p] = true
+	}
+
+	pc.mu.Lock()
+	pc.entries[role] = cachedPermissions{perms: perms, loadedAt: time.Now()}
+	pc.mu.Unlock()
+	return perms, nil
+}
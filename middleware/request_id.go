@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength -> X-Request-ID dari luar yang lebih panjang dari ini diganti
+const maxRequestIDLength = 128
+
+// RequestID -> setiap request punya ID yang dikembalikan di header X-Request-ID
+// dan ikut dicatat di audit_log. ID dari proxy/klien dipakai ulang kalau aman.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Locals("request_id", id)
+		c.Set(fiber.HeaderXRequestID, id)
+		return c.Next()
+	}
+}
+
+// CurrentRequestID -> ID request ini, kosong kalau RequestID tidak dipasang
+func CurrentRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("request_id").(string)
+	return id
+}
+
+// validRequestID -> hanya huruf, angka, '-', '_' dan '.', supaya nilai dari luar
+// tidak bisa menyisipkan apa pun ke log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -3,49 +3,157 @@ package routes
 import (
 	"tugas5/app/repository"
 	"tugas5/app/services"
+	"tugas5/config"
 	"tugas5/database"
 	"tugas5/middleware"
+	"tugas5/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-	// UserRoutes -> definisi route untuk user
-	func UserRoutes(app *fiber.App) {
+// UserRoutes -> definisi route untuk user
+func UserRoutes(app *fiber.App, cfg *config.Config) {
 	alumniRepo := repository.NewAlumniRepository(database.DB)
 	pekerjaanRepo := repository.NewPekerjaanRepository(database.DB)
-	
-	api := app.Group("/api")
-	app.Get("/users", services.GetUsersService)
+	userRepo := repository.NewCachedUserRepository(repository.NewUserRepository(database.DB), cfg.Auth.UserCacheTTL)
+	sessionRepo := repository.NewSessionRepository(database.DB)
+	permissionRepo := repository.NewPermissionRepository(database.DB)
+	passwordResetRepo := repository.NewPasswordResetRepository(database.DB)
+	loginThrottleRepo := repository.NewLoginThrottleRepository(database.DB)
+	twoFactorRepo := repository.NewTwoFactorRepository(database.DB)
+	apiKeyRepo := repository.NewAPIKeyRepository(database.DB)
+	oidcStateRepo := repository.NewOIDCStateRepository(database.DB)
+	auditRepo := repository.NewAuditRepository(database.DB)
+	revisionRepo := repository.NewRevisionRepository(database.DB)
+	mailer := utils.NewMailer(cfg.Mail)
+
+	// Akun direktori kampus dicek dulu, akun lokal tetap jadi cadangan
+	authenticator := repository.NewDBAuthenticator(database.DB)
+	if cfg.LDAP.Enabled() {
+		authenticator = repository.NewFallbackAuthenticator(
+			repository.NewLDAPAuthenticator(cfg.LDAP, userRepo), authenticator)
+	}
+
+	// Kunci publik JWT selalu di root host, sesuai konvensi .well-known
+	app.Get("/.well-known/jwks.json", services.JWKSService)
+
+	root := app.Group(cfg.App.BasePath)
+	api := root.Group("/api")
 
 	// Init service
-	alumniSvc := services.NewAlumniService(alumniRepo)
-	pekerjaanSvc := services.NewPekerjaanService(pekerjaanRepo)
+	changeLog := services.NewChangeLog(auditRepo, revisionRepo)
+	unitOfWork := repository.NewUnitOfWork(database.DB)
+	alumniSvc := services.NewAlumniService(alumniRepo, unitOfWork, changeLog)
+	pekerjaanSvc := services.NewPekerjaanService(pekerjaanRepo, unitOfWork, changeLog)
+	userSvc := services.NewUserService(userRepo, alumniRepo, sessionRepo, permissionRepo)
+	profileSvc := services.NewProfileService(userRepo, alumniRepo, sessionRepo, unitOfWork, changeLog)
+	authSvc := services.NewAuthService(authenticator, userRepo, sessionRepo, loginThrottleRepo, cfg)
+	registrationSvc := services.NewRegistrationService(userRepo, alumniRepo, mailer, cfg)
+	passwordResetSvc := services.NewPasswordResetService(userRepo, passwordResetRepo, unitOfWork, mailer, cfg)
+	twoFactorSvc := services.NewTwoFactorService(authSvc, userRepo, twoFactorRepo)
+	apiKeySvc := services.NewAPIKeyService(apiKeyRepo, permissionRepo)
+	sessionSvc := services.NewSessionService(sessionRepo)
+	impersonationSvc := services.NewImpersonationService(userRepo, permissionRepo, auditRepo, cfg.Auth.ImpersonationTTL)
+	auditSvc := services.NewAuditService(auditRepo)
 
 	// ---------- AUTH ----------
-	api.Post("/login", func(c *fiber.Ctx) error {
-		return services.LoginService(c, database.DB)
-	})
+	// Login password tetap tersedia walaupun SSO aktif
+	api.Post("/login", authSvc.LoginService)
+	api.Post("/login/2fa", twoFactorSvc.LoginVerifyService)
+	api.Post("/login/2fa/enroll", twoFactorSvc.LoginEnrollService)
+	api.Post("/login/2fa/enroll/confirm", twoFactorSvc.LoginEnrollConfirmService)
+	api.Post("/refresh", authSvc.RefreshService)
+	if cfg.OIDC.Enabled() {
+		oidcSvc := services.NewOIDCService(authSvc, userRepo, oidcStateRepo, cfg.OIDC)
+		api.Get("/oidc/login", oidcSvc.LoginService)
+		api.Get("/oidc/callback", oidcSvc.CallbackService)
+	}
+	api.Post("/register", registrationSvc.RegisterService)
+	api.Post("/register/resend", registrationSvc.ResendService)
+	api.Get("/register/verify", registrationSvc.VerifyService)
+	api.Post("/password/forgot", passwordResetSvc.ForgotService)
+	api.Post("/password/reset", passwordResetSvc.ResetService)
+
+	protected := api.Group("", middleware.AuthRequired(middleware.AuthConfig{
+		Users:       userRepo,
+		Sessions:    sessionRepo,
+		Permissions: permissionRepo,
+		APIKeys:     apiKeyRepo,
+		Audit:       auditRepo,
+
+		PasswordChangePath: cfg.App.BasePath + "/api/profile/password",
+	}))
+
+	// Route akun hanya untuk login manusia, bukan API key integrasi
+	human := middleware.HumanOnly()
+	// Impersonasi hanya untuk melihat sistem dari sisi user: semua route yang
+	// mengubah data atau pengaturan akun ditolak selama impersonasi
+	noImpersonation := middleware.NotImpersonating()
+	protected.Get("/profile", human, profileSvc.GetProfileService)
+	protected.Put("/profile", human, noImpersonation, profileSvc.UpdateProfileService)
+	protected.Post("/profile/password", human, noImpersonation, profileSvc.ChangePasswordService)
+	protected.Post("/logout", human, authSvc.LogoutService)
+	protected.Get("/sessions", human, sessionSvc.GetAllService)
+	protected.Delete("/sessions/:id", human, noImpersonation, sessionSvc.RevokeService)
+	protected.Post("/2fa/setup", human, noImpersonation, twoFactorSvc.SetupService)
+	protected.Post("/2fa/enable", human, noImpersonation, twoFactorSvc.EnableService)
+	protected.Post("/2fa/disable", human, noImpersonation, twoFactorSvc.DisableService)
+	protected.Post("/2fa/recovery-codes", human, noImpersonation, twoFactorSvc.RegenerateRecoveryCodesService)
+
+	// Permission tiap route dideklarasikan di sini; aturan kepemilikan data
+	// (misalnya hanya pekerjaan buatan sendiri) tetap dicek di service.
+	require := middleware.Require
+
+	// ---------- ADMIN ----------
+	adminUsers := protected.Group("/admin/users", noImpersonation, require("users:manage"))
+	adminUsers.Get("/", userSvc.GetAllService)
+	adminUsers.Post("/", userSvc.CreateService)
+	adminUsers.Get("/:id", userSvc.GetByIDService)
+	adminUsers.Put("/:id/role", userSvc.UpdateRoleService)
+	adminUsers.Put("/:id/deactivate", userSvc.DeactivateService)
+	adminUsers.Put("/:id/activate", userSvc.ActivateService)
+	adminUsers.Post("/:id/reset-password", userSvc.ResetPasswordService)
+	adminUsers.Put("/:id/alumni", userSvc.LinkAlumniService)
+	adminUsers.Delete("/:id/alumni", userSvc.UnlinkAlumniService)
+	adminUsers.Put("/:id/ldap", userSvc.LinkLDAPService)
+	adminUsers.Delete("/:id/ldap", userSvc.UnlinkLDAPService)
+	adminUsers.Get("/:id/sessions", sessionSvc.GetUserSessionsService)
+	adminUsers.Delete("/:id/sessions", authSvc.RevokeUserSessionsService)
+	adminUsers.Post("/:id/unlock", authSvc.UnlockUserService)
+	adminUsers.Delete("/:id/2fa", require("users:reset_2fa"), twoFactorSvc.AdminResetService)
+
+	// API key tidak bisa membuat API key baru
+	protected.Get("/admin/api-keys", human, noImpersonation, require("api_keys:manage"), apiKeySvc.GetAllService)
+	protected.Post("/admin/api-keys", human, noImpersonation, require("api_keys:manage"), apiKeySvc.CreateService)
+	protected.Delete("/admin/api-keys/:id", human, noImpersonation, require("api_keys:manage"), apiKeySvc.RevokeService)
+
+	protected.Post("/admin/impersonate/:user_id", human, noImpersonation, require("users:impersonate"), impersonationSvc.StartService)
+
+	protected.Get("/admin/audit", require("audit:read"), auditSvc.GetAllService)
 
-	protected := api.Group("", middleware.AuthRequired())
-	protected.Get("/profile", services.GetProfileHandler)
+	protected.Get("/admin/lockouts", noImpersonation, require("users:manage"), authSvc.GetLockoutsService)
+	protected.Delete("/admin/lockouts", noImpersonation, require("users:manage"), authSvc.ClearLockoutService)
 
 	// ---------- ALUMNI ----------
-	protected.Get("/alumni", alumniSvc.GetAllService)
-	protected.Get("/alumni/:id", alumniSvc.GetByIDService)
-	protected.Post("/alumni", middleware.AuthRequired(), alumniSvc.CreateService)
-	protected.Put("/alumni/:id", middleware.AuthRequired(), alumniSvc.UpdateService)
-	protected.Delete("/alumni/:id", middleware.AuthRequired(), alumniSvc.DeleteService)
+	protected.Get("/alumni", require("alumni:read"), alumniSvc.GetAllService)
+	protected.Get("/alumni/:id", require("alumni:read"), alumniSvc.GetByIDService)
+	protected.Post("/alumni", noImpersonation, require("alumni:create"), alumniSvc.CreateService)
+	protected.Put("/alumni/:id", noImpersonation, require("alumni:write"), alumniSvc.UpdateService)
+	protected.Delete("/alumni/:id", noImpersonation, require("alumni:delete"), alumniSvc.DeleteService)
+	protected.Get("/alumni/:id/history", require("alumni:read"), alumniSvc.HistoryService)
+	protected.Post("/alumni/:id/revert/:revision", noImpersonation, require("revisions:revert"), alumniSvc.RevertService)
 
 	// ---------- PEKERJAAN ----------
-	protected.Get("/pekerjaan", pekerjaanSvc.GetAllService)
-	protected.Get("/pekerjaan/trash", middleware.AuthRequired(), pekerjaanSvc.GetTrashService)
-	protected.Get("/pekerjaan/trash/:id", middleware.AuthRequired(), pekerjaanSvc.GetTrashByIDService)
-	protected.Get("/pekerjaan/alumni/:alumni_id", pekerjaanSvc.GetByAlumniIDService)
-	protected.Get("/pekerjaan/:id", pekerjaanSvc.GetByIDService)
-	protected.Post("/pekerjaan", middleware.AuthRequired(), pekerjaanSvc.CreateService)
-	protected.Put("/pekerjaan/:id", middleware.AuthRequired(), pekerjaanSvc.UpdateService)
-	protected.Delete("/pekerjaan/:id", middleware.AuthRequired(), pekerjaanSvc.DeleteService)
-	protected.Put("/pekerjaan/restore/:id", middleware.AuthRequired(), pekerjaanSvc.RestoreService)
-	protected.Delete("/pekerjaan/hard-delete/:id", middleware.AuthRequired(), pekerjaanSvc.HardDeleteService)
-
-}
\ No newline at end of file
+	protected.Get("/pekerjaan", require("pekerjaan:read"), pekerjaanSvc.GetAllService)
+	protected.Get("/pekerjaan/trash", require("pekerjaan:read"), pekerjaanSvc.GetTrashService)
+	protected.Get("/pekerjaan/trash/:id", require("pekerjaan:read"), pekerjaanSvc.GetTrashByIDService)
+	protected.Get("/pekerjaan/alumni/:alumni_id", require("pekerjaan:read"), pekerjaanSvc.GetByAlumniIDService)
+	protected.Get("/pekerjaan/:id", require("pekerjaan:read"), pekerjaanSvc.GetByIDService)
+	protected.Get("/pekerjaan/:id/history", require("pekerjaan:read"), pekerjaanSvc.HistoryService)
+	protected.Post("/pekerjaan", noImpersonation, require("pekerjaan:write"), pekerjaanSvc.CreateService)
+	protected.Put("/pekerjaan/:id", noImpersonation, require("pekerjaan:write"), pekerjaanSvc.UpdateService)
+	protected.Delete("/pekerjaan/:id", noImpersonation, require("pekerjaan:delete"), pekerjaanSvc.DeleteService)
+	protected.Put("/pekerjaan/restore/:id", noImpersonation, require("pekerjaan:restore"), pekerjaanSvc.RestoreService)
+	protected.Delete("/pekerjaan/hard-delete/:id", noImpersonation, require("pekerjaan:hard_delete"), pekerjaanSvc.HardDeleteService)
+	protected.Post("/pekerjaan/:id/revert/:revision", noImpersonation, require("revisions:revert"), pekerjaanSvc.RevertService)
+}
@@ -1,37 +1,127 @@
 package utils
 
 import (
+	"errors"
 	"time"
 	"tugas5/app/model"
+	"tugas5/config"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-var jwtSecret = []byte("secret_key")
+var keys *KeyManager
+var jwtIssuer string
 
-func GenerateToken(user model.User) (string, error) {
-	claims := model.JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Role:     user.Role,
+// AccessTokenTTL -> umur access token; sesi diperpanjang lewat refresh token
+var AccessTokenTTL time.Duration
+
+// InitJWT -> siapkan kunci tanda tangan dari konfigurasi dan jalankan rotasinya,
+// dipanggil sekali di main
+func InitJWT(cfg config.JWTConfig) error {
+	km, err := NewKeyManager(cfg)
+	if err != nil {
+		return err
+	}
+	keys = km
+	jwtIssuer = cfg.Issuer
+	AccessTokenTTL = cfg.AccessTTL
+	go km.Run()
+	return nil
+}
+
+// JWKS -> kunci publik untuk service lain yang memverifikasi token kita
+func JWKS() JSONWebKeySet {
+	return keys.JWKS()
+}
+
+// GenerateToken -> buat access token untuk user pada sesi tertentu.
+// Claims ikut dikembalikan supaya pemanggil bisa menyimpan jti dan expiry-nya.
+func GenerateToken(user model.User, sessionID string) (string, *model.JWTClaims, error) {
+	now := time.Now()
+	claims := &model.JWTClaims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		SessionID: sessionID,
+		Version:   user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    jwtIssuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	signed, err := keys.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// GenerateImpersonationToken -> token berumur pendek atas nama target, dengan
+// admin pembuatnya di claim act. Tidak terikat sesi, jadi tidak bisa di-refresh.
+func GenerateImpersonationToken(target, admin model.User, ttl time.Duration) (string, *model.JWTClaims, error) {
+	now := time.Now()
+	claims := &model.JWTClaims{
+		UserID:   target.ID,
+		Username: target.Username,
+		Role:     target.Role,
+		Version:  target.TokenVersion,
+		Act:      &model.ActorClaim{UserID: admin.ID, Username: admin.Username},
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.NewString(),
+			Issuer:    jwtIssuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	signed, err := keys.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
 func ValidateToken(tokenString string) (*model.JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &model.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &model.JWTClaims{}, keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(jwtIssuer),
+	)
 	if err != nil {
 		return nil, err
 	}
-	if claims, ok := token.Claims.(*model.JWTClaims); ok && token.Valid {
+	// Token aksi (verifikasi email dan sejenisnya) selalu punya audience
+	if claims, ok := token.Claims.(*model.JWTClaims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 	return nil, jwt.ErrInvalidKey
 }
+
+// GenerateActionToken -> token bertanda tangan untuk satu tujuan (purpose) tertentu
+func GenerateActionToken(purpose string, claims model.ActionClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.ID = uuid.NewString()
+	claims.Issuer = jwtIssuer
+	claims.Audience = jwt.ClaimStrings{purpose}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	return keys.Sign(&claims)
+}
+
+// ValidateActionToken -> tolak token yang dibuat untuk tujuan lain
+func ValidateActionToken(purpose, tokenString string) (*model.ActionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &model.ActionClaims{}, keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithAudience(purpose),
+	)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*model.ActionClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("token tidak valid")
+	}
+	return claims, nil
+}
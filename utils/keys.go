@@ -0,0 +1,298 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tugas5/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey -> satu pasangan kunci yang tersimpan sebagai <kid>.pem di JWT_KEY_DIR
+type signingKey struct {
+	kid       string
+	alg       string // RS256 atau EdDSA
+	private   crypto.Signer
+	createdAt time.Time
+	path      string
+}
+
+// KeyManager -> kelola kunci tanda tangan JWT. Kunci terbaru dipakai untuk
+// menandatangani, kunci lama tetap dipakai verifikasi sampai masa grace habis.
+type KeyManager struct {
+	mu          sync.RWMutex
+	dir         string
+	alg         string
+	rotateEvery time.Duration
+	grace       time.Duration
+	keys        []*signingKey // urut dari yang terbaru
+}
+
+// JSONWebKey -> representasi publik satu kunci (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var errUnknownKey = errors.New("kid tidak dikenal")
+
+func NewKeyManager(cfg config.JWTConfig) (*KeyManager, error) {
+	if err := os.MkdirAll(cfg.KeyDir, 0o700); err != nil {
+		return nil, fmt.Errorf("gagal membuat direktori kunci: %w", err)
+	}
+	m := &KeyManager{
+		dir:         cfg.KeyDir,
+		alg:         cfg.Algorithm,
+		rotateEvery: cfg.KeyRotation,
+		grace:       cfg.KeyGrace,
+	}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	if err := m.rotateIfDue(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run -> cek direktori kunci tiap menit: ambil kunci baru dari instance lain,
+// rotasi kalau kunci aktif sudah tua, dan buang kunci yang masa grace-nya habis
+func (m *KeyManager) Run() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := m.reload(); err != nil {
+			log.Println("Gagal memuat ulang kunci JWT:", err)
+			continue
+		}
+		if err := m.rotateIfDue(); err != nil {
+			log.Println("Gagal rotasi kunci JWT:", err)
+		}
+		m.prune()
+	}
+}
+
+// Sign -> tanda tangani claims dengan kunci aktif, kid ikut di header
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	if len(m.keys) == 0 {
+		m.mu.RUnlock()
+		return "", errors.New("tidak ada kunci JWT aktif")
+	}
+	key := m.keys[0]
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.alg), claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc -> pilih kunci publik verifikasi berdasarkan kid di header token
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.keys {
+		if key.kid == kid {
+			if token.Method.Alg() != key.alg {
+				return nil, jwt.ErrTokenSignatureInvalid
+			}
+			return key.private.Public(), nil
+		}
+	}
+	return nil, errUnknownKey
+}
+
+// JWKS -> kunci publik yang masih valid, untuk /.well-known/jwks.json
+func (m *KeyManager) JWKS() JSONWebKeySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range m.keys {
+		jwk := JSONWebKey{Use: "sig", Alg: key.alg, Kid: key.kid}
+		switch pub := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (m *KeyManager) reload() error {
+	paths, err := filepath.Glob(filepath.Join(m.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	var keys []*signingKey
+	for _, path := range paths {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return fmt.Errorf("kunci %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].createdAt.After(keys[j].createdAt) })
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *KeyManager) rotateIfDue() error {
+	m.mu.RLock()
+	due := len(m.keys) == 0 || time.Since(m.keys[0].createdAt) >= m.rotateEvery
+	m.mu.RUnlock()
+	if !due {
+		return nil
+	}
+	return m.Rotate()
+}
+
+// Rotate -> buat kunci baru dan langsung jadikan kunci aktif
+func (m *KeyManager) Rotate() error {
+	key, err := generateSigningKey(m.alg)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return err
+	}
+	key.path = filepath.Join(m.dir, key.kid+".pem")
+
+	// Tulis ke file sementara lalu rename, supaya instance lain tidak membaca file setengah jadi
+	tmp := key.path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, key.path); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.keys = append([]*signingKey{key}, m.keys...)
+	m.mu.Unlock()
+	log.Printf("Kunci JWT baru aktif: kid=%s alg=%s", key.kid, key.alg)
+	return nil
+}
+
+// prune -> kunci yang sudah digantikan lebih lama dari masa grace dihapus
+func (m *KeyManager) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.keys[:0]
+	for i, key := range m.keys {
+		// Kunci ke-i berhenti dipakai saat kunci yang lebih baru (i-1) dibuat
+		if i > 0 && time.Since(m.keys[i-1].createdAt) > m.grace {
+			if err := os.Remove(key.path); err != nil && !os.IsNotExist(err) {
+				log.Println("Gagal menghapus kunci JWT lama:", err)
+			}
+			log.Printf("Kunci JWT pensiun: kid=%s", key.kid)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	m.keys = kept
+}
+
+func generateSigningKey(alg string) (*signingKey, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("algoritma JWT tidak didukung: %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	kid, err := keyID(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{kid: kid, alg: alg, private: private, createdAt: time.Now()}, nil
+}
+
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || !strings.Contains(block.Type, "PRIVATE KEY") {
+		return nil, errors.New("bukan file PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{createdAt: info.ModTime(), path: path}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.alg, key.private = "RS256", k
+	case ed25519.PrivateKey:
+		key.alg, key.private = "EdDSA", k
+	default:
+		return nil, fmt.Errorf("tipe kunci %T tidak didukung", parsed)
+	}
+	if key.kid, err = keyID(key.private.Public()); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// keyID -> kid diturunkan dari hash kunci publik, jadi stabil di semua instance
+func keyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
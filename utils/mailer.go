@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"tugas5/config"
+)
+
+// Mail -> satu email teks biasa
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer -> pengirim email; implementasinya dipilih lewat MAIL_DRIVER
+type Mailer interface {
+	Send(m Mail) error
+}
+
+func NewMailer(cfg config.MailConfig) Mailer {
+	if cfg.Driver == "smtp" {
+		return &smtpMailer{
+			addr:     cfg.SMTPHost + ":" + cfg.SMTPPort,
+			host:     cfg.SMTPHost,
+			from:     cfg.From,
+			username: cfg.SMTPUser,
+			password: cfg.SMTPPassword,
+		}
+	}
+	return &fileMailer{dir: cfg.FileDir, from: cfg.From}
+}
+
+// fileMailer -> tulis email ke file .eml, untuk development dan test
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+func (m *fileMailer) Send(msg Mail) error {
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000"), sanitizeFilename(msg.To))
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0o600)
+}
+
+// smtpMailer -> kirim lewat SMTP, termasuk SMTP catcher lokal seperti MailHog
+type smtpMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+func (m *smtpMailer) Send(msg Mail) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("MAIL_FROM tidak valid: %w", err)
+	}
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	return smtp.SendMail(m.addr, auth, from.Address, []string{msg.To}, buildMessage(m.from, msg))
+}
+
+func buildMessage(from string, msg Mail) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -1,11 +1,16 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"math/big"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password),
-	bcrypt.DefaultCost)
-		return string(bytes), err
+		bcrypt.DefaultCost)
+	return string(bytes), err
 }
 
 func CheckPassword(password, hash string) bool {
@@ -13,6 +18,23 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
+// passwordAlphabet -> tanpa karakter yang mirip (0/O, 1/l/I) supaya mudah dibacakan
+const passwordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateTemporaryPassword -> password sementara untuk reset oleh admin
+func GenerateTemporaryPassword(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
 func StringPtr(s string) *string {
-    return &s
-}
\ No newline at end of file
+	return &s
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// GenerateRandomToken -> token acak 256-bit (refresh token, token reset password),
+// hanya dikirim ke client sekali
+func GenerateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken -> SHA-256 dari token; yang disimpan di database hanya hash ini
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyTag -> penanda awal API key, membedakannya dari JWT di header Authorization
+const apiKeyTag = "ak_"
+
+// GenerateAPIKey -> key berbentuk ak_<prefix>_<secret>. Prefix disimpan apa
+// adanya untuk pencarian, sedangkan key lengkap hanya disimpan hash-nya.
+func GenerateAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(b)
+	return apiKeyTag + prefix + "_" + secret, prefix, nil
+}
+
+// ParseAPIKey -> ambil prefix dari key; ok=false kalau bentuknya bukan API key
+func ParseAPIKey(key string) (prefix string, ok bool) {
+	if !strings.HasPrefix(key, apiKeyTag) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, apiKeyTag), "_", 2)
+	if len(parts) != 2 || len(parts[0]) != 8 || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
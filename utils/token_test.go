@@ -0,0 +1,40 @@
+package utils
+
+import "testing"
+
+func TestParseAPIKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		prefix string
+		ok     bool
+	}{
+		{"valid", "ak_0123abcd_secretpart", "0123abcd", true},
+		{"secret boleh berisi underscore", "ak_0123abcd_se_cret", "0123abcd", true},
+		{"tanpa tag", "0123abcd_secret", "", false},
+		{"tag saja", "ak_", "", false},
+		{"tanpa secret", "ak_0123abcd_", "", false},
+		{"tanpa pemisah", "ak_0123abcdsecret", "", false},
+		{"prefix terlalu pendek", "ak_0123_secret", "", false},
+		{"prefix terlalu panjang", "ak_0123abcdef_secret", "", false},
+		{"JWT", "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln", "", false},
+		{"kosong", "", "", false},
+	}
+	for _, tc := range tests {
+		prefix, ok := ParseAPIKey(tc.key)
+		if prefix != tc.prefix || ok != tc.ok {
+			t.Errorf("%s: ParseAPIKey(%q) = (%q, %v), seharusnya (%q, %v)", tc.name, tc.key, prefix, ok, tc.prefix, tc.ok)
+		}
+	}
+}
+
+func TestGenerateAPIKeyRoundTrip(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := ParseAPIKey(key)
+	if !ok || got != prefix {
+		t.Errorf("ParseAPIKey(%q) = (%q, %v), seharusnya (%q, true)", key, got, ok, prefix)
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vektor uji RFC 6238 lampiran B (HMAC-SHA1). RFC memakai 8 digit; kode 6 digit
+// adalah 6 digit terakhirnya karena keduanya diambil dari nilai yang sama.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		if got := totpCode([]byte("12345678901234567890"), v.unix/totpPeriod); got != v.code {
+			t.Errorf("T=%d: kode %s, seharusnya %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		at := time.Unix(v.unix, 0)
+		step, ok := VerifyTOTP(rfc6238Secret, v.code, at, 0)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("T=%d: VerifyTOTP = (%d, %v), seharusnya (%d, true)", v.unix, step, ok, v.unix/totpPeriod)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	// Satu langkah sebelum dan sesudah masih diterima, dua langkah tidak
+	if _, ok := VerifyTOTP(rfc6238Secret, "050471", at.Add(totpPeriod*time.Second), 0); !ok {
+		t.Error("kode dari langkah sebelumnya seharusnya diterima")
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, "050471", at.Add(-totpPeriod*time.Second), 0); !ok {
+		t.Error("kode dari langkah berikutnya seharusnya diterima")
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, "050471", at.Add(2*totpPeriod*time.Second), 0); ok {
+		t.Error("kode yang sudah lewat dua langkah seharusnya ditolak")
+	}
+}
+
+func TestVerifyTOTPRejectsReplay(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	step, ok := VerifyTOTP(rfc6238Secret, "050471", at, 0)
+	if !ok {
+		t.Fatal("kode valid ditolak")
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, "050471", at, step); ok {
+		t.Error("kode yang langkahnya sudah dipakai seharusnya ditolak")
+	}
+}
+
+func TestVerifyTOTPInvalidInput(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, tc := range []struct{ name, secret, code string }{
+		{"kode salah", rfc6238Secret, "000000"},
+		{"panjang kode salah", rfc6238Secret, "94287082"},
+		{"secret bukan base32", "bukan-base32!", "287082"},
+	} {
+		if _, ok := VerifyTOTP(tc.secret, tc.code, at, 0); ok {
+			t.Errorf("%s: seharusnya ditolak", tc.name)
+		}
+	}
+	// Secret boleh ditulis huruf kecil dan dengan spasi di ujung
+	if _, ok := VerifyTOTP(" "+strings.ToLower(rfc6238Secret)+" ", "287082", at, 0); !ok {
+		t.Error("secret huruf kecil seharusnya diterima")
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import "strings"
+
+// DeviceLabel -> ringkasan User-Agent yang mudah dibaca, mis. "Chrome di Windows".
+// Hanya tebakan untuk ditampilkan ke user, jangan dipakai untuk keputusan keamanan.
+func DeviceLabel(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Tidak diketahui"
+	}
+
+	browser := ""
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "curl/"):
+		return "curl"
+	case strings.HasPrefix(ua, "postmanruntime/"):
+		return "Postman"
+	case strings.HasPrefix(ua, "okhttp/"):
+		browser = "Aplikasi Android"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "mac os x") || strings.Contains(ua, "macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " di " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	// Client lain (script, library HTTP): ambil nama produk di depan
+	name, _, _ := strings.Cut(userAgent, "/")
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}